@@ -3,19 +3,52 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
+	"sync"
+	"time"
 
 	"Gox/config"
 	"Gox/constants"
 	"Gox/logger"
 	"Gox/proxy"
 	"Gox/server"
+	"Gox/subscription"
+	"Gox/traffic"
+
+	"github.com/google/uuid"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	// ProxyStatusEvent 代理状态变化事件名
+	ProxyStatusEvent = "proxy:status"
+	// ProxyTrafficEvent 代理流量统计事件名
+	ProxyTrafficEvent = "proxy:traffic"
+	// TrafficQuotaEvent 服务器流量超过配额事件名
+	TrafficQuotaEvent = "traffic:quota"
+	// SubscriptionExpiryEvent 订阅即将到期事件名
+	SubscriptionExpiryEvent = "subscription:expiry"
+)
+
+const (
+	// subscriptionCheckInterval 检查订阅更新和到期的间隔
+	subscriptionCheckInterval = 10 * time.Minute
+	// gfwListCheckInterval 检查GFWList是否需要刷新的间隔
+	gfwListCheckInterval = time.Hour
 )
 
 // App 应用程序结构体
 type App struct {
 	ctx           context.Context
+	cancel        context.CancelFunc // 停止后台任务
 	serverManager server.ServerManager
 	proxyManager  proxy.ProxyManager
+	trafficStore  *traffic.Store
+	subscriptions *subscription.Manager
+
+	// trafficMu 保护上一次流量统计，用于计算增量
+	trafficMu   sync.Mutex
+	lastTraffic proxy.TrafficStats
 }
 
 // NewApp 创建新的应用程序实例
@@ -49,16 +82,63 @@ func (a *App) startup(ctx context.Context) {
 	// 初始化服务器管理器
 	a.serverManager = server.NewFileServerManager(constants.GetServerDir())
 	// 初始化代理管理器
-	proxyMgr, err := proxy.NewXrayProxyManager(constants.GetAppDir(), XrayBinary)
+	proxyMgr, err := proxy.NewXrayProxyManager(constants.GetAppDir(), CoreBinaries)
 	if err != nil {
 		fmt.Printf("Failed to initialize proxy manager: %v\n", err)
 		return
 	}
 	a.proxyManager = proxyMgr
 
+	// 流量统计失败不影响代理本身
+	if a.trafficStore, err = traffic.NewStore(constants.GetTrafficFilePath()); err != nil {
+		logger.GetSugarLogger().Warnf("Failed to load traffic store: %v", err)
+	}
+
+	// 状态变化推送给前端
+	proxyMgr.OnStatusChange(func(event proxy.StatusEvent) {
+		runtime.EventsEmit(a.ctx, ProxyStatusEvent, event)
+	})
+	// 流量统计推送给前端，并计入当前服务器的用量
+	proxyMgr.OnTrafficStats(func(stats proxy.TrafficStats) {
+		runtime.EventsEmit(a.ctx, ProxyTrafficEvent, stats)
+		a.recordTraffic(stats)
+	})
+
+	// 初始化订阅管理器
+	if a.subscriptions, err = subscription.NewManager(constants.GetSubscriptionFilePath(), nil); err != nil {
+		logger.GetSugarLogger().Warnf("Failed to load subscriptions: %v", err)
+	}
+
+	// 后台任务在应用退出时停止
+	var background context.Context
+	background, a.cancel = context.WithCancel(ctx)
+	// 后台按配置的间隔刷新GFWList
+	go a.runGFWListUpdates(background)
+	// 后台按服务商要求的间隔更新订阅
+	go a.runSubscriptionUpdates(background)
+
 	logger.GetSugarLogger().Info("Application started successfully")
 }
 
+// shutdown 应用程序退出时调用，停止代理避免Xray继续占用端口
+func (a *App) shutdown(ctx context.Context) {
+	if a.cancel != nil {
+		a.cancel()
+	}
+	// 代理停止时PAC服务一并关闭；应用不修改系统代理设置，无需额外恢复
+	if a.proxyManager != nil {
+		if err := a.proxyManager.StopProxy(); err != nil {
+			logger.GetSugarLogger().Warnf("Failed to stop proxy on shutdown: %v", err)
+		}
+	}
+	if a.trafficStore != nil {
+		if err := a.trafficStore.Flush(); err != nil {
+			logger.GetSugarLogger().Warnf("Failed to save traffic store: %v", err)
+		}
+	}
+	logger.Sync()
+}
+
 // Greet 问候方法
 func (a *App) Greet(name string) string {
 	return fmt.Sprintf("Hello %s, It's show time!", name)
@@ -70,8 +150,18 @@ func (a *App) GetConfig() *config.Config {
 }
 
 // UpdateConfig 更新应用程序配置
+// 内核初始化失败时代理管理器为空，此时仍允许保存配置，以便修正内核路径等设置
 func (a *App) UpdateConfig(cfg *config.Config) error {
-	return config.UpdateConfig(cfg)
+	if a.proxyManager != nil {
+		if err := a.proxyManager.ValidateXrayConfig(cfg.Proxy); err != nil {
+			return err
+		}
+	}
+	if err := config.UpdateConfig(cfg); err != nil {
+		return err
+	}
+	a.refreshPAC()
+	return nil
 }
 
 // GetLogLines 获取日志行
@@ -99,6 +189,18 @@ func (a *App) RemoveServer(name string) error {
 	return a.serverManager.RemoveServer(name)
 }
 
+// ImportServerLink 从分享链接导入服务器
+func (a *App) ImportServerLink(link string) (*server.ServerConfig, error) {
+	serverConfig, err := server.ParseShareLink(link)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.serverManager.AddServer(serverConfig); err != nil {
+		return nil, err
+	}
+	return serverConfig, nil
+}
+
 // ValidateServerName 验证服务器名称是否重复
 func (a *App) ValidateServerName(name string, excludeID string) error {
 	return a.serverManager.ValidateServerName(name, excludeID)
@@ -111,7 +213,7 @@ func (a *App) StartProxy(serverName string) error {
 	if err != nil {
 		return err
 	}
-	
+
 	// 启动代理
 	return a.proxyManager.Start(serverConfig)
 }
@@ -121,7 +223,377 @@ func (a *App) StopProxy() error {
 	return a.proxyManager.Stop()
 }
 
-// GetProxyStatus 获取代理状态
-func (a *App) GetProxyStatus() proxy.ProxyStatus {
-	return a.proxyManager.GetStatus()
+// GetProxyStatus 获取代理状态详情
+func (a *App) GetProxyStatus() proxy.StatusInfo {
+	return a.proxyManager.GetStatusInfo()
+}
+
+// TestRoute 测试目标地址会命中的路由规则
+func (a *App) TestRoute(target string) (*proxy.RouteTestResult, error) {
+	return a.proxyManager.TestRoute(target)
+}
+
+// RefreshBlockLists 重新下载远程拦截列表
+func (a *App) RefreshBlockLists() ([]proxy.BlockListStatus, error) {
+	statuses, err := a.proxyManager.RefreshBlockLists()
+	if err != nil {
+		return nil, err
+	}
+	a.refreshPAC()
+	return statuses, nil
+}
+
+// GetBlockListStatus 获取拦截列表状态
+func (a *App) GetBlockListStatus() []proxy.BlockListStatus {
+	return a.proxyManager.GetBlockListStatus()
+}
+
+// ListRoutingRules 获取自定义路由规则
+func (a *App) ListRoutingRules() []config.RoutingRuleConfig {
+	return config.GetConfig().Proxy.RoutingRules
+}
+
+// ValidateRoutingRule 校验自定义路由规则
+func (a *App) ValidateRoutingRule(rule config.RoutingRuleConfig) error {
+	return a.proxyManager.ValidateRoutingRule(rule)
+}
+
+// SaveRoutingRules 校验并保存自定义路由规则，启用的规则必须能被当前内核执行
+func (a *App) SaveRoutingRules(rules []config.RoutingRuleConfig) error {
+	for i := range rules {
+		if rules[i].ID == "" {
+			rules[i].ID = uuid.New().String()
+		}
+		if !rules[i].Enabled {
+			continue
+		}
+		if err := a.proxyManager.ValidateRoutingRule(rules[i]); err != nil {
+			return err
+		}
+	}
+
+	cfg := *config.GetConfig()
+	cfg.Proxy.RoutingRules = rules
+	return a.UpdateConfig(&cfg)
+}
+
+// ImportGFWList 从本地路径或URL导入GFWList并替换已有的GFWList规则
+func (a *App) ImportGFWList(source string) (*proxy.GFWListImportResult, error) {
+	data, err := proxy.FetchRuleSource(source)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, result, err := proxy.ParseGFWList(data)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := *config.GetConfig()
+	cfg.Proxy.RoutingRules = proxy.ReplaceGFWListRules(cfg.Proxy.RoutingRules, rules)
+	cfg.Proxy.GFWList.Source = source
+	cfg.Proxy.GFWList.UpdatedAt = result.UpdatedAt
+	if err := a.UpdateConfig(&cfg); err != nil {
+		return nil, err
+	}
+
+	logger.GetSugarLogger().Infof("Imported GFWList from %s: %d proxy, %d direct, %d ip, %d unconverted",
+		source, result.ProxyCount, result.DirectCount, result.IPCount, len(result.Unconverted))
+	return result, nil
+}
+
+// RefreshGFWList 从已配置的来源重新导入GFWList
+func (a *App) RefreshGFWList() (*proxy.GFWListImportResult, error) {
+	source := config.GetConfig().Proxy.GFWList.Source
+	if source == "" {
+		return nil, fmt.Errorf("GFWList source is not configured")
+	}
+	return a.ImportGFWList(source)
+}
+
+// runGFWListUpdates 定时检查GFWList，每次检查时重新读取配置，修改更新间隔后无需重启
+func (a *App) runGFWListUpdates(ctx context.Context) {
+	ticker := time.NewTicker(gfwListCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		a.refreshGFWListIfStale()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshGFWListIfStale 已导入过的GFWList超过更新间隔时自动刷新
+func (a *App) refreshGFWListIfStale() {
+	gfwList := config.GetConfig().Proxy.GFWList
+	if gfwList.Source == "" || gfwList.UpdateInterval <= 0 || gfwList.UpdatedAt.IsZero() {
+		return
+	}
+	if time.Since(gfwList.UpdatedAt) < time.Duration(gfwList.UpdateInterval)*time.Hour {
+		return
+	}
+	if _, err := a.RefreshGFWList(); err != nil {
+		logger.GetSugarLogger().Warnf("Failed to refresh GFWList: %v", err)
+	}
+}
+
+// GetPACURL 获取PAC地址，PAC服务未运行时返回空字符串
+func (a *App) GetPACURL() string {
+	return a.proxyManager.GetPACURL()
+}
+
+// refreshPAC 路由规则或路由模式变化后重新生成PAC
+func (a *App) refreshPAC() {
+	if a.proxyManager == nil {
+		return
+	}
+	if err := a.proxyManager.RefreshPAC(); err != nil {
+		logger.GetSugarLogger().Warnf("Failed to refresh PAC: %v", err)
+	}
+}
+
+// GetLANInfo 获取局域网共享地址和认证信息
+func (a *App) GetLANInfo() (*proxy.LANInfo, error) {
+	return a.proxyManager.GetLANInfo()
+}
+
+// RegenerateLANCredentials 重新生成局域网认证信息，需重启代理后生效
+func (a *App) RegenerateLANCredentials() (*proxy.LANCredentials, error) {
+	return a.proxyManager.RegenerateLANCredentials()
+}
+
+// QueryAccessLog 查询连接记录，可按目标地址或出站过滤
+func (a *App) QueryAccessLog(query proxy.AccessLogQuery) ([]proxy.AccessRecord, error) {
+	return a.proxyManager.QueryAccessLog(query), nil
+}
+
+// GetCrashHistory 获取Xray崩溃及自动重启记录
+func (a *App) GetCrashHistory() ([]proxy.CrashRecord, error) {
+	return a.proxyManager.GetCrashHistory(), nil
+}
+
+// GetCoreInfo 获取当前使用的Xray内核路径、版本和来源
+func (a *App) GetCoreInfo() proxy.CoreInfo {
+	return a.proxyManager.GetCoreInfo()
+}
+
+// ListCoreVersions 列出已安装的Xray版本
+func (a *App) ListCoreVersions() ([]proxy.CoreVersion, error) {
+	return a.proxyManager.ListCoreVersions()
+}
+
+// InstallCoreVersion 从本地zip或URL安装Xray版本，checksum为空时使用发布包的.dgst文件校验
+func (a *App) InstallCoreVersion(source, checksum string) (*proxy.CoreVersion, error) {
+	version, err := a.proxyManager.InstallCoreVersion(source, checksum)
+	if err != nil {
+		return nil, err
+	}
+	logger.GetSugarLogger().Infof("Installed xray %s from %s", version.Version, source)
+	return version, nil
+}
+
+// ActivateCoreVersion 切换使用的Xray版本，空字符串表示默认内核，重启代理后生效
+func (a *App) ActivateCoreVersion(version string) (proxy.CoreInfo, error) {
+	return a.proxyManager.ActivateCoreVersion(version)
+}
+
+// RollbackCoreVersion 切换回上一个使用的Xray版本，重启代理后生效
+func (a *App) RollbackCoreVersion() (proxy.CoreInfo, error) {
+	return a.proxyManager.RollbackCoreVersion()
+}
+
+// GetCoreCapabilities 获取各内核支持的协议、传输方式和功能
+func (a *App) GetCoreCapabilities() []proxy.CoreCapabilities {
+	return a.proxyManager.GetCoreCapabilities()
+}
+
+// ListConnections 列出经过代理的活动连接，当前内核不支持时返回错误
+func (a *App) ListConnections() ([]proxy.Connection, error) {
+	return a.proxyManager.ListConnections()
+}
+
+// CloseConnection 关闭指定连接
+func (a *App) CloseConnection(id string) error {
+	return a.proxyManager.CloseConnection(id)
+}
+
+// CloseConnectionsToHost 关闭所有发往指定域名或IP的连接，返回关闭的连接数
+func (a *App) CloseConnectionsToHost(host string) (int, error) {
+	count, err := a.proxyManager.CloseConnectionsToHost(host)
+	if err != nil {
+		return count, err
+	}
+	logger.GetSugarLogger().Infof("Closed %d connections to %s", count, host)
+	return count, nil
+}
+
+// SpeedTestServer 通过临时内核测试服务器的下载带宽，serverID为空时测试当前服务器
+// 多个测速依次执行，测速失败或被取消时原因记录在结果中
+func (a *App) SpeedTestServer(serverID string) (proxy.SpeedTestResult, error) {
+	srv := a.proxyManager.GetActiveServer()
+	if serverID != "" {
+		var err error
+		if srv, err = a.serverManager.GetServer(serverID); err != nil {
+			return proxy.SpeedTestResult{}, err
+		}
+	}
+	if srv == nil {
+		return proxy.SpeedTestResult{}, fmt.Errorf("没有正在使用的服务器")
+	}
+
+	result := a.proxyManager.SpeedTest(srv)
+	if result.Error != "" {
+		logger.GetSugarLogger().Warnf("Speed test of %s failed: %s", srv.Name, result.Error)
+	} else {
+		logger.GetSugarLogger().Infof("Speed test of %s: %.2f Mbps, TTFB %d ms", srv.Name, result.Mbps, result.TTFB)
+	}
+	return result, nil
+}
+
+// CancelSpeedTest 取消正在运行和排队等待的测速
+func (a *App) CancelSpeedTest() {
+	a.proxyManager.CancelSpeedTest()
+}
+
+// recordTraffic 将本次运行累计流量的增量计入采样时的服务器及其所属订阅，本月用量首次超过配额时通知前端
+func (a *App) recordTraffic(stats proxy.TrafficStats) {
+	a.trafficMu.Lock()
+	upload := stats.TotalUpload - a.lastTraffic.TotalUpload
+	download := stats.TotalDownload - a.lastTraffic.TotalDownload
+	// 累计值变小说明代理已重新启动
+	if upload < 0 || download < 0 {
+		upload, download = stats.TotalUpload, stats.TotalDownload
+	}
+	a.lastTraffic = stats
+	a.trafficMu.Unlock()
+
+	srv := stats.Server
+	if a.trafficStore == nil || srv == nil {
+		return
+	}
+	sample := traffic.Sample{
+		ServerID:       srv.ID,
+		ServerName:     srv.Name,
+		SubscriptionID: srv.SubscriptionID,
+		Upload:         upload,
+		Download:       download,
+		Quota:          int64(srv.MonthlyQuotaMB) * 1024 * 1024,
+		Time:           stats.Timestamp,
+	}
+	if srv.SubscriptionID != "" && a.subscriptions != nil {
+		if sub, err := a.subscriptions.Get(srv.SubscriptionID); err == nil {
+			sample.SubscriptionName = sub.Name
+		}
+	}
+	alert, err := a.trafficStore.Record(sample)
+	if err != nil {
+		logger.GetSugarLogger().Warnf("Failed to save traffic store: %v", err)
+	}
+	if alert != nil {
+		logger.GetSugarLogger().Warnf("Server %s used %d bytes in %s, exceeding its quota of %d bytes",
+			alert.ServerName, alert.Used, alert.Month, alert.Quota)
+		runtime.EventsEmit(a.ctx, TrafficQuotaEvent, alert)
+	}
+}
+
+// QueryTraffic 按服务器或订阅和时间范围查询流量用量
+func (a *App) QueryTraffic(query traffic.UsageQuery) ([]traffic.UsagePoint, error) {
+	if a.trafficStore == nil {
+		return nil, fmt.Errorf("流量统计不可用")
+	}
+	return a.trafficStore.Query(query)
+}
+
+// ExportTrafficCSV 选择保存位置后将流量用量导出为CSV，返回保存路径，取消时返回空字符串
+func (a *App) ExportTrafficCSV(query traffic.UsageQuery) (string, error) {
+	if a.trafficStore == nil {
+		return "", fmt.Errorf("流量统计不可用")
+	}
+
+	path, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		DefaultFilename: fmt.Sprintf("traffic-%s.csv", time.Now().Format("20060102")),
+		Filters:         []runtime.FileFilter{{DisplayName: "CSV (*.csv)", Pattern: "*.csv"}},
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create csv file: %w", err)
+	}
+	defer file.Close()
+	if err := a.trafficStore.ExportCSV(file, query); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// runSubscriptionUpdates 定时更新到期的订阅，并提醒即将到期的订阅
+func (a *App) runSubscriptionUpdates(ctx context.Context) {
+	if a.subscriptions == nil {
+		return
+	}
+	ticker := time.NewTicker(subscriptionCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, err := range a.subscriptions.RefreshDue() {
+			logger.GetSugarLogger().Warnf("Failed to refresh subscription: %v", err)
+		}
+		warnings, err := a.subscriptions.ExpiryWarnings()
+		if err != nil {
+			logger.GetSugarLogger().Warnf("Failed to save subscriptions: %v", err)
+		}
+		for _, warning := range warnings {
+			logger.GetSugarLogger().Warnf("Subscription %s expires at %s", warning.Name, warning.UserInfo.Expire.Format(time.DateTime))
+			runtime.EventsEmit(a.ctx, SubscriptionExpiryEvent, warning)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ListSubscriptions 列出订阅及其剩余流量和到期时间
+func (a *App) ListSubscriptions() ([]subscription.Status, error) {
+	if a.subscriptions == nil {
+		return nil, fmt.Errorf("订阅管理不可用")
+	}
+	return a.subscriptions.List(), nil
+}
+
+// AddSubscription 添加订阅并立即更新一次，更新失败时订阅仍会保留
+func (a *App) AddSubscription(name, url string) (*subscription.Status, error) {
+	if a.subscriptions == nil {
+		return nil, fmt.Errorf("订阅管理不可用")
+	}
+	sub, err := a.subscriptions.Add(name, url)
+	if err != nil {
+		return nil, err
+	}
+	return a.subscriptions.Refresh(sub.ID)
+}
+
+// RemoveSubscription 删除订阅
+func (a *App) RemoveSubscription(id string) error {
+	if a.subscriptions == nil {
+		return fmt.Errorf("订阅管理不可用")
+	}
+	return a.subscriptions.Remove(id)
+}
+
+// RefreshSubscription 立即更新订阅的流量和到期信息
+func (a *App) RefreshSubscription(id string) (*subscription.Status, error) {
+	if a.subscriptions == nil {
+		return nil, fmt.Errorf("订阅管理不可用")
+	}
+	return a.subscriptions.Refresh(id)
 }
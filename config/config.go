@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"sync"
+	"time"
 
 	"Gox/constants"
 )
@@ -18,10 +19,115 @@ type LogConfig struct {
 
 // ProxyConfig 代理配置结构
 type ProxyConfig struct {
-	XrayBinaryPath string `json:"xrayBinaryPath"` // Xray二进制路径
-	XrayConfig     string `json:"xrayConfig"`     // Xray配置
-	RouteMode      string `json:"routeMode"`      // AsIs, GeoIP
-	GeoIPPath      string `json:"geoIPPath"`      // GeoIP文件路径
+	XrayBinaryPath    string `json:"xrayBinaryPath"`    // Xray二进制路径
+	XrayConfig        string `json:"xrayConfig"`        // Xray配置
+	RouteMode         string `json:"routeMode"`         // AsIs, GeoIP
+	GeoIPPath         string `json:"geoIPPath"`         // GeoIP文件路径
+	Core              string `json:"core"`              // 默认内核: xray, sing-box
+	SingBoxBinaryPath string `json:"singBoxBinaryPath"` // sing-box二进制路径
+
+	Inbound InboundConfig `json:"inbound"` // 本地入站配置
+	LAN     LANConfig     `json:"lan"`     // 局域网共享配置
+
+	FakeDNS        FakeDNSConfig     `json:"fakeDNS"`        // FakeDNS配置
+	BlockLists     []BlockListConfig `json:"blockLists"`     // 广告/追踪拦截列表
+	BlockAllowlist []string          `json:"blockAllowlist"` // 拦截白名单，优先于拦截列表
+
+	RoutingRules []RoutingRuleConfig `json:"routingRules"` // 自定义路由规则，按顺序匹配
+	GFWList      GFWListConfig       `json:"gfwList"`      // GFWList规则来源
+	PAC          PACConfig           `json:"pac"`          // PAC服务配置
+	AccessLog    AccessLogConfig     `json:"accessLog"`    // 访问日志配置
+	AutoRestart  AutoRestartConfig   `json:"autoRestart"`  // Xray崩溃后自动重启策略
+	SpeedTest    SpeedTestConfig     `json:"speedTest"`    // 带宽测速配置
+}
+
+// InboundConfig 本地入站配置
+type InboundConfig struct {
+	Listen       string   `json:"listen"`       // 监听地址
+	SocksPort    int      `json:"socksPort"`    // SOCKS端口，0表示不启用
+	HTTPPort     int      `json:"httpPort"`     // HTTP端口，0表示不启用
+	MixedPort    int      `json:"mixedPort"`    // 混合端口(SOCKS+HTTP)，0表示不启用
+	APIPort      int      `json:"apiPort"`      // Xray API端口，仅监听回环地址
+	UDP          bool     `json:"udp"`          // SOCKS是否转发UDP
+	Sniffing     bool     `json:"sniffing"`     // 是否启用流量探测
+	DestOverride []string `json:"destOverride"` // 探测协议(http, tls, quic)
+	RouteOnly    bool     `json:"routeOnly"`    // 探测结果仅用于路由，不改写目标地址
+}
+
+// LANConfig 局域网共享配置，用户名密码单独保存在权限受限的文件中
+type LANConfig struct {
+	Enabled     bool   `json:"enabled"`     // 是否允许局域网连接
+	Interface   string `json:"interface"`   // 绑定的网卡名称，为空则监听所有地址
+	AuthEnabled bool   `json:"authEnabled"` // SOCKS/HTTP入站是否要求用户名密码
+}
+
+// PACConfig PAC服务配置
+type PACConfig struct {
+	Enabled bool `json:"enabled"` // 是否启用本地PAC服务
+	Port    int  `json:"port"`    // PAC服务端口
+}
+
+// AccessLogConfig 访问日志配置
+type AccessLogConfig struct {
+	Enabled    bool `json:"enabled"`    // 是否记录Xray访问日志
+	MaxRecords int  `json:"maxRecords"` // 内存中保留的连接记录数
+}
+
+// AutoRestartConfig Xray崩溃后自动重启策略
+type AutoRestartConfig struct {
+	Enabled       bool `json:"enabled"`       // 是否自动重启
+	MaxAttempts   int  `json:"maxAttempts"`   // 时间窗口内最多重启次数
+	WindowSeconds int  `json:"windowSeconds"` // 统计重启次数的时间窗口(秒)
+}
+
+// SubscriptionConfig 订阅更新和到期提醒配置
+type SubscriptionConfig struct {
+	UpdateInterval    int `json:"updateInterval"`    // 订阅未指定更新间隔时的默认间隔(小时)
+	ExpiryWarningDays int `json:"expiryWarningDays"` // 到期前多少天开始提醒，0表示不提醒
+}
+
+// SpeedTestConfig 带宽测速配置，下载达到时长或字节数任一上限即结束
+type SpeedTestConfig struct {
+	URL      string `json:"url"`      // 测速下载地址
+	Duration int    `json:"duration"` // 下载时长(秒)
+	MaxMB    int    `json:"maxMB"`    // 最多下载的数据量(MB)，0表示只按时长限制
+}
+
+// GFWListConfig GFWList规则来源配置
+type GFWListConfig struct {
+	Source         string    `json:"source"`         // 本地路径或URL
+	UpdateInterval int       `json:"updateInterval"` // 自动更新间隔(小时)，0表示不自动更新
+	UpdatedAt      time.Time `json:"updatedAt"`      // 上次导入时间
+}
+
+// RoutingRuleConfig 自定义路由规则结构
+type RoutingRuleConfig struct {
+	ID          string   `json:"id"`          // 规则ID
+	Name        string   `json:"name"`        // 规则名称
+	Type        string   `json:"type"`        // domain, ip, process
+	Values      []string `json:"values"`      // 匹配条目
+	OutboundTag string   `json:"outboundTag"` // proxy, direct, block
+	Enabled     bool     `json:"enabled"`     // 是否启用
+}
+
+// FakeDNSConfig FakeDNS配置结构
+type FakeDNSConfig struct {
+	Enabled      bool     `json:"enabled"`      // 是否启用FakeDNS
+	IPv4Pool     string   `json:"ipv4Pool"`     // IPv4地址池(CIDR)
+	IPv4PoolSize int      `json:"ipv4PoolSize"` // IPv4地址池大小
+	IPv6Pool     string   `json:"ipv6Pool"`     // IPv6地址池(CIDR)，为空则不启用
+	IPv6PoolSize int      `json:"ipv6PoolSize"` // IPv6地址池大小
+	DNSServers   []string `json:"dnsServers"`   // 非FakeDNS查询使用的上游DNS
+	QueryPort    int      `json:"queryPort"`    // 本地DNS查询端口（供路由测试解析假IP）
+}
+
+// BlockListConfig 拦截列表配置结构
+type BlockListConfig struct {
+	ID      string `json:"id"`      // 列表ID
+	Name    string `json:"name"`    // 列表名称
+	Type    string `json:"type"`    // list: hosts或域名列表文件, geosite: geosite分类
+	Source  string `json:"source"`  // 本地路径、URL或geosite分类名(如 category-ads-all)
+	Enabled bool   `json:"enabled"` // 是否启用
 }
 
 // TUNConfig TUN配置结构
@@ -39,14 +145,83 @@ type Config struct {
 	Language   string `json:"language"`   // 语言设置
 
 	// 模块配置
-	Log   LogConfig   `json:"log"`   // 日志配置
-	Proxy ProxyConfig `json:"proxy"` // 代理配置
-	TUN   TUNConfig   `json:"tun"`   // TUN配置
+	Log          LogConfig          `json:"log"`          // 日志配置
+	Proxy        ProxyConfig        `json:"proxy"`        // 代理配置
+	TUN          TUNConfig          `json:"tun"`          // TUN配置
+	Subscription SubscriptionConfig `json:"subscription"` // 订阅配置
 
 	// 服务器列表
 	Servers []ServerConfig `json:"servers"`
 }
 
+const (
+	// DefaultFakeDNSIPv4Pool 默认FakeDNS IPv4地址池
+	DefaultFakeDNSIPv4Pool = "198.18.0.0/15"
+	// DefaultFakeDNSIPv6Pool 默认FakeDNS IPv6地址池
+	DefaultFakeDNSIPv6Pool = "fc00::/18"
+	// DefaultFakeDNSPoolSize 默认FakeDNS地址池大小
+	DefaultFakeDNSPoolSize = 65535
+	// DefaultFakeDNSQueryPort 默认FakeDNS本地查询端口
+	DefaultFakeDNSQueryPort = 10853
+
+	// BlockListTypeList hosts或域名列表文件
+	BlockListTypeList = "list"
+	// BlockListTypeGeosite geosite分类
+	BlockListTypeGeosite = "geosite"
+
+	// CoreXray Xray内核
+	CoreXray = "xray"
+	// CoreSingBox sing-box内核
+	CoreSingBox = "sing-box"
+
+	// DefaultListenAddress 默认入站监听地址
+	DefaultListenAddress = "127.0.0.1"
+	// DefaultSocksPort 默认SOCKS端口
+	DefaultSocksPort = 1080
+	// DefaultHTTPPort 默认HTTP端口
+	DefaultHTTPPort = 1081
+	// DefaultAPIPort 默认Xray API端口
+	DefaultAPIPort = 10085
+
+	// RouteModeAsIs 仅按域名分流
+	RouteModeAsIs = "AsIs"
+	// RouteModeGeoIP 域名未命中时解析IP再按GeoIP分流
+	RouteModeGeoIP = "GeoIP"
+
+	// DefaultPACPort 默认PAC服务端口
+	DefaultPACPort = 10810
+
+	// DefaultAccessLogRecords 默认保留的连接记录数
+	DefaultAccessLogRecords = 1000
+
+	// DefaultRestartAttempts 默认时间窗口内最多重启次数
+	DefaultRestartAttempts = 5
+	// DefaultRestartWindowSeconds 默认重启统计窗口(秒)
+	DefaultRestartWindowSeconds = 300
+
+	// DefaultSubscriptionUpdateInterval 默认订阅更新间隔(小时)
+	DefaultSubscriptionUpdateInterval = 24
+	// DefaultSubscriptionExpiryWarningDays 默认订阅到期提醒提前天数
+	DefaultSubscriptionExpiryWarningDays = 3
+
+	// DefaultSpeedTestURL 默认测速下载地址
+	DefaultSpeedTestURL = "https://speed.cloudflare.com/__down?bytes=200000000"
+	// DefaultSpeedTestDuration 默认测速下载时长(秒)
+	DefaultSpeedTestDuration = 10
+	// DefaultSpeedTestMaxMB 默认测速最多下载的数据量(MB)
+	DefaultSpeedTestMaxMB = 100
+
+	// DefaultGFWListURL 默认GFWList地址
+	DefaultGFWListURL = "https://raw.githubusercontent.com/gfwlist/gfwlist/master/gfwlist.txt"
+
+	// RuleTypeDomain 按域名匹配
+	RuleTypeDomain = "domain"
+	// RuleTypeIP 按IP匹配
+	RuleTypeIP = "ip"
+	// RuleTypeProcess 按进程名或可执行文件路径匹配
+	RuleTypeProcess = "process"
+)
+
 // ServerConfig 服务器配置
 type ServerConfig struct {
 	ID       string `json:"id"`       // 服务器ID
@@ -75,16 +250,80 @@ func GetDefaultConfig() *Config {
 			Path:    constants.GetLogDir(),
 		},
 		Proxy: ProxyConfig{
-			XrayBinaryPath: "",
-			XrayConfig:     "",
-			RouteMode:      "AsIs",
-			GeoIPPath:      "",
+			XrayBinaryPath:    "",
+			XrayConfig:        "",
+			RouteMode:         RouteModeAsIs,
+			GeoIPPath:         "",
+			Core:              CoreXray,
+			SingBoxBinaryPath: "",
+			Inbound: InboundConfig{
+				Listen:       DefaultListenAddress,
+				SocksPort:    DefaultSocksPort,
+				HTTPPort:     DefaultHTTPPort,
+				APIPort:      DefaultAPIPort,
+				MixedPort:    0,
+				UDP:          true,
+				Sniffing:     true,
+				DestOverride: []string{"http", "tls"},
+				RouteOnly:    false,
+			},
+			LAN: LANConfig{
+				Enabled:     false,
+				Interface:   "",
+				AuthEnabled: true,
+			},
+			FakeDNS: FakeDNSConfig{
+				Enabled:      false,
+				IPv4Pool:     DefaultFakeDNSIPv4Pool,
+				IPv4PoolSize: DefaultFakeDNSPoolSize,
+				IPv6Pool:     DefaultFakeDNSIPv6Pool,
+				IPv6PoolSize: DefaultFakeDNSPoolSize,
+				DNSServers:   []string{"8.8.8.8", "1.1.1.1"},
+				QueryPort:    DefaultFakeDNSQueryPort,
+			},
+			BlockLists: []BlockListConfig{
+				{
+					ID:      "category-ads-all",
+					Name:    "广告域名",
+					Type:    BlockListTypeGeosite,
+					Source:  "category-ads-all",
+					Enabled: false,
+				},
+			},
+			BlockAllowlist: []string{},
+			RoutingRules:   []RoutingRuleConfig{},
+			GFWList: GFWListConfig{
+				Source:         DefaultGFWListURL,
+				UpdateInterval: 0,
+			},
+			PAC: PACConfig{
+				Enabled: false,
+				Port:    DefaultPACPort,
+			},
+			AccessLog: AccessLogConfig{
+				Enabled:    true,
+				MaxRecords: DefaultAccessLogRecords,
+			},
+			AutoRestart: AutoRestartConfig{
+				Enabled:       true,
+				MaxAttempts:   DefaultRestartAttempts,
+				WindowSeconds: DefaultRestartWindowSeconds,
+			},
+			SpeedTest: SpeedTestConfig{
+				URL:      DefaultSpeedTestURL,
+				Duration: DefaultSpeedTestDuration,
+				MaxMB:    DefaultSpeedTestMaxMB,
+			},
 		},
 		TUN: TUNConfig{
 			DeviceName: "tun0",
 			IPAddress:  "10.0.0.1",
 			Subnet:     "10.0.0.0/24",
 		},
+		Subscription: SubscriptionConfig{
+			UpdateInterval:    DefaultSubscriptionUpdateInterval,
+			ExpiryWarningDays: DefaultSubscriptionExpiryWarningDays,
+		},
 		Servers: []ServerConfig{},
 	}
 }
@@ -112,8 +351,8 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
-	// 尝试解析为新格式
-	var config Config
+	// 尝试解析为新格式，缺失的字段保留默认值
+	config := *GetDefaultConfig()
 	if err := json.Unmarshal(data, &config); err != nil {
 		return nil, err
 	}
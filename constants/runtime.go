@@ -12,6 +12,14 @@ const (
 	ConfigFileName = "config.json"
 	// LogFileName 日志文件名称
 	LogFileName = "app.log"
+	// CoreLogFileName Xray内核日志文件名称
+	CoreLogFileName = "xray.log"
+	// AccessLogFileName Xray访问日志文件名称
+	AccessLogFileName = "access.log"
+	// TrafficFileName 流量统计文件名称
+	TrafficFileName = "traffic.json"
+	// SubscriptionFileName 订阅列表文件名称
+	SubscriptionFileName = "subscriptions.json"
 )
 
 var (
@@ -27,6 +35,14 @@ var (
 	ConfigFilePath string
 	// LogFilePath 日志文件完整路径
 	LogFilePath string
+	// CoreLogFilePath Xray内核日志文件完整路径
+	CoreLogFilePath string
+	// AccessLogFilePath Xray访问日志文件完整路径
+	AccessLogFilePath string
+	// TrafficFilePath 流量统计文件完整路径
+	TrafficFilePath string
+	// SubscriptionFilePath 订阅列表文件完整路径
+	SubscriptionFilePath string
 )
 
 // InitRuntimePaths 初始化运行时路径
@@ -45,6 +61,10 @@ func InitRuntimePaths() error {
 	// 设置文件路径
 	ConfigFilePath = filepath.Join(ConfigDir, ConfigFileName)
 	LogFilePath = filepath.Join(LogDir, LogFileName)
+	CoreLogFilePath = filepath.Join(LogDir, CoreLogFileName)
+	AccessLogFilePath = filepath.Join(LogDir, AccessLogFileName)
+	TrafficFilePath = filepath.Join(AppDir, TrafficFileName)
+	SubscriptionFilePath = filepath.Join(ConfigDir, SubscriptionFileName)
 
 	// 创建必要的目录
 	if err := os.MkdirAll(ConfigDir, 0755); err != nil {
@@ -85,7 +105,27 @@ func GetLogFilePath() string {
 	return LogFilePath
 }
 
+// GetCoreLogFilePath 获取Xray内核日志文件路径
+func GetCoreLogFilePath() string {
+	return CoreLogFilePath
+}
+
+// GetAccessLogFilePath 获取Xray访问日志文件路径
+func GetAccessLogFilePath() string {
+	return AccessLogFilePath
+}
+
 // GetServerDir 获取服务器配置目录
 func GetServerDir() string {
 	return ServerDir
-}
\ No newline at end of file
+}
+
+// GetTrafficFilePath 获取流量统计文件路径
+func GetTrafficFilePath() string {
+	return TrafficFilePath
+}
+
+// GetSubscriptionFilePath 获取订阅列表文件路径
+func GetSubscriptionFilePath() string {
+	return SubscriptionFilePath
+}
@@ -2,6 +2,8 @@ package main
 
 import "embed"
 
-// XrayBinary 嵌入的xray.exe二进制文件
-//go:embed resources/xray.exe
-var XrayBinary embed.FS
\ No newline at end of file
+// CoreBinaries 嵌入的内核二进制文件，按 xray-<系统>-<架构> 命名，Windows下兼容 xray.exe
+// 没有当前平台的二进制时会回退到PATH中的xray
+//
+//go:embed resources
+var CoreBinaries embed.FS
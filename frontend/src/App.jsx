@@ -1,4 +1,4 @@
-import React from 'react'
+import React, { useEffect } from 'react'
 import { motion, AnimatePresence } from 'framer-motion'
 import MainLayout from './components/MainLayout'
 import ThemeProvider from './components/ThemeProvider'
@@ -13,7 +13,18 @@ import useAppStore from './store/useAppStore'
  * 主应用组件
  */
 function App() {
-  const { currentPage } = useAppStore()
+  const { currentPage, refreshProxyStatus, subscribeProxyStatus, subscribeTrafficStats } = useAppStore()
+
+  // 同步当前代理状态并订阅后端推送的状态变化和流量统计
+  useEffect(() => {
+    refreshProxyStatus()
+    const unsubscribeStatus = subscribeProxyStatus()
+    const unsubscribeTraffic = subscribeTrafficStats()
+    return () => {
+      unsubscribeStatus()
+      unsubscribeTraffic()
+    }
+  }, [])
 
   // 页面切换动画配置
   const pageVariants = {
@@ -3,13 +3,34 @@ import TitleBar from './TitleBar'
 import Navigation from './Navigation'
 import StatusBar from './StatusBar'
 import useAppStore from '../store/useAppStore'
+import { useToast } from './ui/Toast'
 import { cn } from '../lib/utils'
 
 /**
  * 主布局组件
  */
 const MainLayout = ({ children }) => {
-  const { theme, themeColor } = useAppStore()
+  const { theme, themeColor, subscribeTrafficQuota, subscribeSubscriptionExpiry } = useAppStore()
+  const { toast } = useToast()
+
+  // 服务器本月流量超过配额时提醒
+  useEffect(() => {
+    return subscribeTrafficQuota((alert) => {
+      const used = (alert.used / 1024 / 1024).toFixed(0)
+      const quota = (alert.quota / 1024 / 1024).toFixed(0)
+      toast.warning('流量超出配额', `${alert.serverName} 本月已用 ${used} MB，配额 ${quota} MB`)
+    })
+  }, [])
+
+  // 订阅即将到期时提醒
+  useEffect(() => {
+    return subscribeSubscriptionExpiry((warning) => {
+      const description = warning.expired
+        ? `${warning.name} 已到期`
+        : `${warning.name} 将在 ${warning.daysLeft} 天后到期`
+      toast.warning('订阅即将到期', description)
+    })
+  }, [])
 
   // 应用主题到document
   useEffect(() => {
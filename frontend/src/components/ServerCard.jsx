@@ -2,7 +2,7 @@ import React from 'react'
 import { Card, CardContent, CardHeader, CardTitle } from './ui/card'
 import { Button } from './ui/button'
 import { Badge } from './ui/badge'
-import { Copy, Edit, Trash2, Play, Pause, Wifi, WifiOff } from 'lucide-react'
+import { Copy, Edit, Trash2, Play, Pause, Wifi, WifiOff, Gauge, Loader2 } from 'lucide-react'
 import useAppStore from '../store/useAppStore'
 
 /**
@@ -12,8 +12,10 @@ import useAppStore from '../store/useAppStore'
  * @param {Function} onDelete - 删除回调
  * @param {Function} onCopy - 复制回调
  * @param {Function} onConnect - 连接回调
+ * @param {Function} onSpeedTest - 测速回调，测速中再次点击取消
+ * @param {boolean} speedTesting - 是否正在测速
  */
-const ServerCard = ({ server, onEdit, onDelete, onCopy, onConnect }) => {
+const ServerCard = ({ server, onEdit, onDelete, onCopy, onConnect, onSpeedTest, speedTesting }) => {
   const { proxyStatus, activeServer } = useAppStore()
   
   // 判断是否为当前活跃服务器
@@ -136,6 +138,17 @@ const ServerCard = ({ server, onEdit, onDelete, onCopy, onConnect }) => {
             >
               <Trash2 className="w-3 h-3" />
             </Button>
+            {server.protocol !== 'custom' && (
+              <Button 
+                size="sm" 
+                variant="outline" 
+                onClick={() => onSpeedTest(server)}
+                title={speedTesting ? '取消测速' : '带宽测速'}
+                className="cursor-pointer"
+              >
+                {speedTesting ? <Loader2 className="w-3 h-3 animate-spin" /> : <Gauge className="w-3 h-3" />}
+              </Button>
+            )}
           </div>
           
           <Button 
@@ -8,7 +8,7 @@ import { Badge } from './ui/Badge'
  * 底部状态栏组件
  */
 const StatusBar = () => {
-  const { activeServer, proxyStatus, systemStats } = useAppStore()
+  const { activeServer, proxyStatus, proxyError, systemStats } = useAppStore()
 
   const formatBytes = (bytes) => {
     if (bytes === 0) return '0 B'
@@ -36,6 +36,8 @@ const StatusBar = () => {
         return '运行中'
       case 'connecting':
         return '连接中'
+      case 'error':
+        return '错误'
       case 'stopped':
       default:
         return '已停止'
@@ -59,10 +61,8 @@ const StatusBar = () => {
             proxyStatus === 'running' ? 'success' :
             proxyStatus === 'connecting' ? 'warning' :
             'destructive'
-          }>
-            {proxyStatus === 'running' ? '运行中' :
-             proxyStatus === 'connecting' ? '连接中' :
-             '已停止'}
+          } title={proxyStatus === 'error' ? proxyError : undefined}>
+            {getStatusText()}
           </Badge>
         </div>
 
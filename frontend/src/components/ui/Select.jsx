@@ -145,7 +145,7 @@ SelectContent.displayName = "SelectContent"
 /**
  * SelectItem组件
  */
-const SelectItem = React.forwardRef(({ className, children, value, ...props }, ref) => {
+const SelectItem = React.forwardRef(({ className, children, value, disabled = false, ...props }, ref) => {
   const { value: selectedValue, onValueChange } = React.useContext(SelectContext)
   const isSelected = selectedValue === value
   
@@ -155,16 +155,18 @@ const SelectItem = React.forwardRef(({ className, children, value, ...props }, r
       className={cn(
         "relative flex w-full cursor-default select-none items-center rounded-sm py-1.5 pl-8 pr-2 text-sm outline-none hover:bg-accent hover:text-accent-foreground focus:bg-accent focus:text-accent-foreground transition-colors",
         isSelected && "bg-accent text-accent-foreground",
+        disabled && "pointer-events-none opacity-50",
         className
       )}
-      onClick={() => onValueChange(value)}
-      whileHover={{
+      aria-disabled={disabled}
+      onClick={() => !disabled && onValueChange(value)}
+      whileHover={!disabled ? {
         backgroundColor: "hsl(var(--accent))",
         color: "hsl(var(--accent-foreground))"
-      }}
-      whileTap={{
+      } : {}}
+      whileTap={!disabled ? {
         scale: 0.98
-      }}
+      } : {}}
       transition={{
         duration: 0.15
       }}
@@ -18,6 +18,7 @@ import { GetLogLines } from '../../wailsjs/go/main/App'
 const LogsPage = () => {
   const { logs, clearLogs, addLog } = useAppStore()
   const [filter, setFilter] = useState('all')
+  const [componentFilter, setComponentFilter] = useState('all')
   const [searchTerm, setSearchTerm] = useState('')
   const [autoScroll, setAutoScroll] = useState(true)
   const [isLoading, setIsLoading] = useState(false)
@@ -44,7 +45,8 @@ const LogsPage = () => {
         timestamp: logObj.timestamp || new Date().toISOString(),
         level: (logObj.level || 'info').toLowerCase(),
         message: logObj.msg || line,
-        source: logObj.caller || ''
+        source: logObj.caller || '',
+        component: logObj.component || 'app'
       }
     } catch {
       // 如果不是JSON格式，尝试解析文本格式
@@ -54,7 +56,8 @@ const LogsPage = () => {
           timestamp: match[1],
           level: match[2].toLowerCase(),
           message: match[3],
-          source: ''
+          source: '',
+          component: 'app'
         }
       }
       // 默认格式
@@ -62,7 +65,8 @@ const LogsPage = () => {
         timestamp: new Date().toISOString(),
         level: 'info',
         message: line,
-        source: ''
+        source: '',
+        component: 'app'
       }
     }
   }
@@ -91,10 +95,12 @@ const LogsPage = () => {
   // 过滤日志
   const filteredLogs = allLogs.filter(log => {
     const matchesFilter = filter === 'all' || log.level === filter
+    // 区分应用日志和Xray内核日志，store中的日志都来自应用
+    const matchesComponent = componentFilter === 'all' || (log.component || 'app') === componentFilter
     const matchesSearch = searchTerm === '' || 
       log.message.toLowerCase().includes(searchTerm.toLowerCase()) ||
       log.source?.toLowerCase().includes(searchTerm.toLowerCase())
-    return matchesFilter && matchesSearch
+    return matchesFilter && matchesComponent && matchesSearch
   })
 
   // 组件挂载时加载日志
@@ -194,6 +200,18 @@ const LogsPage = () => {
                 />
               </div>
             </div>
+            <div className="w-full sm:w-48">
+              <Select value={componentFilter} onValueChange={setComponentFilter}>
+                <SelectTrigger>
+                  <SelectValue />
+                </SelectTrigger>
+                <SelectContent>
+                  <SelectItem value="all">所有来源</SelectItem>
+                  <SelectItem value="app">应用日志</SelectItem>
+                  <SelectItem value="xray">内核日志</SelectItem>
+                </SelectContent>
+              </Select>
+            </div>
             <div className="w-full sm:w-48">
               <Select value={filter} onValueChange={setFilter}>
                 <SelectTrigger>
@@ -251,6 +269,11 @@ const LogsPage = () => {
                   <Badge variant={getLogLevelVariant(log.level)} className="text-xs">
                     {log.level.toUpperCase()}
                   </Badge>
+                  {log.component === 'xray' && (
+                    <Badge variant="outline" className="text-xs">
+                      XRAY
+                    </Badge>
+                  )}
                   {log.source && (
                     <span className="text-primary text-xs font-medium">
                       [{log.source}]
@@ -5,9 +5,29 @@ import { Input } from '../components/ui/input'
 import { Label } from '../components/ui/label'
 import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from '../components/ui/select'
 import { useToast } from '../components/ui/Toast'
-import { Server, Plus, Loader2 } from 'lucide-react'
+import { Server, Plus, Loader2, Link } from 'lucide-react'
 import useAppStore from '../store/useAppStore'
 import ServerCard from '../components/ServerCard'
+import { GetCoreCapabilities, ListSubscriptions, SpeedTestServer, CancelSpeedTest } from '../../wailsjs/go/main/App'
+
+// 协议选项
+const PROTOCOL_OPTIONS = [
+  { value: 'vmess', label: 'VMess' },
+  { value: 'vless', label: 'VLESS' },
+  { value: 'trojan', label: 'Trojan' },
+  { value: 'hysteria2', label: 'Hysteria2' },
+  { value: 'tuic', label: 'TUIC' },
+  { value: 'custom', label: '完整配置' }
+]
+
+// 传输协议选项
+const NETWORK_OPTIONS = [
+  { value: 'tcp', label: 'TCP' },
+  { value: 'ws', label: 'WebSocket' },
+  { value: 'h2', label: 'HTTP/2' },
+  { value: 'grpc', label: 'gRPC' },
+  { value: 'httpupgrade', label: 'HTTPUpgrade' }
+]
 
 /**
  * 服务器管理页面组件
@@ -21,6 +41,7 @@ const ServersPage = () => {
     updateServer, 
     removeServer, 
     validateServerName,
+    importServerLink,
     startProxy,
     stopProxy,
     refreshProxyStatus,
@@ -32,6 +53,9 @@ const ServersPage = () => {
   const [editingServer, setEditingServer] = useState(null)
   const [loading, setLoading] = useState(false)
   const [submitting, setSubmitting] = useState(false)
+  const [coreCapabilities, setCoreCapabilities] = useState([])
+  const [subscriptions, setSubscriptions] = useState([])
+  const [speedTesting, setSpeedTesting] = useState({})
   const [formData, setFormData] = useState({
     name: '',
     protocol: 'vmess',
@@ -51,7 +75,20 @@ const ServersPage = () => {
     // 通用字段
     tls: false,
     skipCertVerify: false,
-    serverName: ''
+    serverName: '',
+    core: '',
+    monthlyQuotaMB: 0,
+    subscriptionId: '',
+    // Hysteria2 / TUIC 特有字段
+    alpn: [],
+    obfs: '',
+    obfsPassword: '',
+    upMbps: 0,
+    downMbps: 0,
+    congestionControl: 'bbr',
+    udpRelayMode: 'native',
+    // 完整配置
+    config: ''
   })
 
   // 重置表单
@@ -75,7 +112,20 @@ const ServersPage = () => {
       // 通用字段
       tls: false,
       skipCertVerify: false,
-      serverName: ''
+      serverName: '',
+      core: '',
+      monthlyQuotaMB: 0,
+    subscriptionId: '',
+      // Hysteria2 / TUIC 特有字段
+      alpn: [],
+      obfs: '',
+      obfsPassword: '',
+      upMbps: 0,
+      downMbps: 0,
+      congestionControl: 'bbr',
+      udpRelayMode: 'native',
+      // 完整配置
+      config: ''
     })
     setShowAddForm(false)
     setEditingServer(null)
@@ -91,7 +141,10 @@ const ServersPage = () => {
       case 'vless':
         return [...commonFields, 'id', 'encryption', 'flow', 'tls', 'skipCertVerify', 'serverName']
       case 'trojan':
+      case 'hysteria2':
         return [...commonFields, 'password', 'sni', 'skipCertVerify']
+      case 'tuic':
+        return [...commonFields, 'id', 'password', 'sni', 'skipCertVerify']
       default:
         return commonFields
     }
@@ -111,8 +164,8 @@ const ServersPage = () => {
     if (!formData.port || formData.port < 1 || formData.port > 65535) {
       errors.push('端口号必须在1-65535之间')
     }
-    // 用户ID验证（trojan协议不需要用户ID）
-    if (formData.protocol !== 'trojan' && !formData.id.trim()) {
+    // 用户ID验证（trojan、hysteria2和完整配置不需要用户ID）
+    if (!['trojan', 'hysteria2', 'custom'].includes(formData.protocol) && !formData.id.trim()) {
       errors.push('用户ID不能为空')
     }
     
@@ -136,11 +189,48 @@ const ServersPage = () => {
           errors.push('Trojan密码不能为空')
         }
         break
+      case 'hysteria2':
+      case 'tuic':
+        if (!formData.password.trim()) {
+          errors.push('密码不能为空')
+        }
+        break
+      case 'custom':
+        if (!formData.config?.trim()) {
+          errors.push('完整配置不能为空')
+        } else {
+          try {
+            JSON.parse(formData.config)
+          } catch (error) {
+            errors.push('完整配置不是有效的JSON')
+          }
+        }
+        break
+    }
+
+    // 内核能力验证
+    if (coreCapabilities.length > 0 && !supportsProtocol(formData.protocol)) {
+      errors.push('所选内核不支持该协议')
     }
     
     return errors
   }
 
+  // 当前表单可用的内核：指定内核时只看该内核，否则看所有可用内核
+  const candidateCores = (core = formData.core) => {
+    return coreCapabilities.filter(c => c.available && (!core || c.core === core))
+  }
+
+  // 是否有内核支持该协议
+  const supportsProtocol = (protocol, core) => {
+    return candidateCores(core).some(c => c.protocols?.includes(protocol))
+  }
+
+  // 是否有内核同时支持当前协议和该传输方式
+  const supportsNetwork = (network) => {
+    return candidateCores().some(c => c.protocols?.includes(formData.protocol) && c.transports?.includes(network))
+  }
+
   // 页面初始化
   useEffect(() => {
     const initPage = async () => {
@@ -148,6 +238,8 @@ const ServersPage = () => {
       try {
         await loadServers()
         await refreshProxyStatus()
+        setCoreCapabilities(await GetCoreCapabilities() || [])
+        setSubscriptions(await ListSubscriptions() || [])
       } catch (error) {
         console.error('Failed to initialize page:', error)
         toast.error('初始化失败', '无法加载服务器列表')
@@ -204,6 +296,21 @@ const ServersPage = () => {
     }
   }
 
+  // 从分享链接导入服务器
+  const handleImportLink = async () => {
+    const link = prompt('粘贴 hysteria2:// 或 tuic:// 分享链接')
+    if (!link || !link.trim()) {
+      return
+    }
+    try {
+      const server = await importServerLink(link.trim())
+      toast.success('导入成功', `${server.name} 已添加到服务器列表`)
+    } catch (error) {
+      console.error('Import error:', error)
+      toast.error('导入失败', error.message || String(error))
+    }
+  }
+
   // 开始编辑服务器
   const startEdit = (server) => {
     setFormData(server)
@@ -232,6 +339,36 @@ const ServersPage = () => {
     toast.success('配置已复制', '服务器配置已复制到剪贴板')
   }
 
+  // 带宽测速，多个测速在后端依次执行；测速中再次点击取消所有测速
+  const handleSpeedTest = async (server) => {
+    if (speedTesting[server.id]) {
+      await CancelSpeedTest()
+      return
+    }
+    setSpeedTesting(prev => ({ ...prev, [server.id]: true }))
+    try {
+      const result = await SpeedTestServer(server.id)
+      if (result.cancelled) {
+        toast.info('测速已取消', server.name)
+      } else if (result.error) {
+        toast.error(`${server.name} 测速失败`, result.error)
+      } else {
+        toast.success(
+          `${server.name} 测速完成`,
+          `${result.mbps.toFixed(2)} Mbps，首字节 ${result.ttfb} ms`
+        )
+      }
+    } catch (error) {
+      toast.error('测速失败', error.message || String(error))
+    } finally {
+      setSpeedTesting(prev => {
+        const next = { ...prev }
+        delete next[server.id]
+        return next
+      })
+    }
+  }
+
   // 连接/断开服务器
   const handleConnect = async (server) => {
     try {
@@ -261,13 +398,23 @@ const ServersPage = () => {
           <Server className="w-6 h-6 text-primary" />
           <h1 className="text-2xl font-bold">服务器管理</h1>
         </div>
-        <Button 
-          className="bg-theme hover:bg-theme/50 text-theme-foreground cursor-pointer"
-          onClick={() => setShowAddForm(true)}
-        >
-          <Plus className="w-4 h-4 mr-2" />
-          添加服务器
-        </Button>
+        <div className="flex items-center space-x-2">
+          <Button 
+            variant="outline"
+            className="cursor-pointer"
+            onClick={handleImportLink}
+          >
+            <Link className="w-4 h-4 mr-2" />
+            导入链接
+          </Button>
+          <Button 
+            className="bg-theme hover:bg-theme/50 text-theme-foreground cursor-pointer"
+            onClick={() => setShowAddForm(true)}
+          >
+            <Plus className="w-4 h-4 mr-2" />
+            添加服务器
+          </Button>
+        </div>
       </div>
 
       {/* 添加/编辑表单 */}
@@ -297,9 +444,36 @@ const ServersPage = () => {
                       <SelectValue />
                     </SelectTrigger>
                     <SelectContent>
-                      <SelectItem value="vmess">VMess</SelectItem>
-                      <SelectItem value="vless">VLESS</SelectItem>
-                      <SelectItem value="trojan">Trojan</SelectItem>
+                      {PROTOCOL_OPTIONS.map(option => (
+                        <SelectItem
+                          key={option.value}
+                          value={option.value}
+                          disabled={coreCapabilities.length > 0 && !supportsProtocol(option.value)}
+                        >
+                          {option.label}
+                        </SelectItem>
+                      ))}
+                    </SelectContent>
+                  </Select>
+                </div>
+                <div className="space-y-2">
+                  <Label htmlFor="core">内核</Label>
+                  <Select value={formData.core || ''} onValueChange={(value) => setFormData({ ...formData, core: value })}>
+                    <SelectTrigger>
+                      <SelectValue placeholder="跟随全局设置" />
+                    </SelectTrigger>
+                    <SelectContent>
+                      <SelectItem value="">跟随全局设置</SelectItem>
+                      {coreCapabilities.map(c => (
+                        <SelectItem
+                          key={c.core}
+                          value={c.core}
+                          disabled={!c.available || !c.protocols?.includes(formData.protocol)}
+                          title={c.available ? '' : c.error}
+                        >
+                          {c.core}{c.version ? ` ${c.version}` : ''}
+                        </SelectItem>
+                      ))}
                     </SelectContent>
                   </Select>
                 </div>
@@ -324,6 +498,31 @@ const ServersPage = () => {
                     required
                   />
                 </div>
+                <div className="space-y-2">
+                  <Label htmlFor="monthlyQuotaMB">每月流量配额 (MB)</Label>
+                  <Input
+                    id="monthlyQuotaMB"
+                    type="number"
+                    min="0"
+                    value={formData.monthlyQuotaMB || 0}
+                    onChange={(e) => setFormData({ ...formData, monthlyQuotaMB: parseInt(e.target.value) || 0 })}
+                    placeholder="0 表示不限制"
+                  />
+                </div>
+                <div className="space-y-2">
+                  <Label htmlFor="subscriptionId">所属订阅</Label>
+                  <Select value={formData.subscriptionId || ''} onValueChange={(value) => setFormData({ ...formData, subscriptionId: value })}>
+                    <SelectTrigger>
+                      <SelectValue placeholder="不属于任何订阅" />
+                    </SelectTrigger>
+                    <SelectContent>
+                      <SelectItem value="">不属于任何订阅</SelectItem>
+                      {subscriptions.map(s => (
+                        <SelectItem key={s.id} value={s.id}>{s.name}</SelectItem>
+                      ))}
+                    </SelectContent>
+                  </Select>
+                </div>
 
                 {/* VMess 协议特有字段 */}
                 {formData.protocol === 'vmess' && (
@@ -369,10 +568,15 @@ const ServersPage = () => {
                           <SelectValue />
                         </SelectTrigger>
                         <SelectContent>
-                          <SelectItem value="tcp">TCP</SelectItem>
-                          <SelectItem value="ws">WebSocket</SelectItem>
-                          <SelectItem value="h2">HTTP/2</SelectItem>
-                          <SelectItem value="grpc">gRPC</SelectItem>
+                          {NETWORK_OPTIONS.map(option => (
+                            <SelectItem
+                              key={option.value}
+                              value={option.value}
+                              disabled={coreCapabilities.length > 0 && !supportsNetwork(option.value)}
+                            >
+                              {option.label}
+                            </SelectItem>
+                          ))}
                         </SelectContent>
                       </Select>
                     </div>
@@ -445,6 +649,131 @@ const ServersPage = () => {
                   </>
                 )}
 
+                {/* Hysteria2 / TUIC 协议特有字段 */}
+                {(formData.protocol === 'hysteria2' || formData.protocol === 'tuic') && (
+                  <>
+                    {formData.protocol === 'tuic' && (
+                      <div className="space-y-2">
+                        <Label htmlFor="id">用户ID (UUID)</Label>
+                        <Input
+                          id="id"
+                          value={formData.id}
+                          onChange={(e) => setFormData({ ...formData, id: e.target.value })}
+                          placeholder="输入用户ID"
+                          required
+                        />
+                      </div>
+                    )}
+                    <div className="space-y-2">
+                      <Label htmlFor="password">密码</Label>
+                      <Input
+                        id="password"
+                        type="password"
+                        value={formData.password}
+                        onChange={(e) => setFormData({ ...formData, password: e.target.value })}
+                        placeholder="输入密码"
+                        required
+                      />
+                    </div>
+                    <div className="space-y-2">
+                      <Label htmlFor="sni">SNI</Label>
+                      <Input
+                        id="sni"
+                        value={formData.sni}
+                        onChange={(e) => setFormData({ ...formData, sni: e.target.value })}
+                        placeholder="输入SNI域名"
+                      />
+                    </div>
+                    <div className="space-y-2">
+                      <Label htmlFor="alpn">ALPN</Label>
+                      <Input
+                        id="alpn"
+                        value={(formData.alpn || []).join(',')}
+                        onChange={(e) => setFormData({ ...formData, alpn: e.target.value.split(',').map(v => v.trim()).filter(Boolean) })}
+                        placeholder={formData.protocol === 'tuic' ? '默认 h3' : '可选，逗号分隔'}
+                      />
+                    </div>
+                    {formData.protocol === 'hysteria2' && (
+                      <>
+                        <div className="space-y-2">
+                          <Label htmlFor="obfsPassword">Salamander混淆密码</Label>
+                          <Input
+                            id="obfsPassword"
+                            value={formData.obfsPassword}
+                            onChange={(e) => setFormData({ ...formData, obfs: e.target.value ? 'salamander' : '', obfsPassword: e.target.value })}
+                            placeholder="不使用混淆时留空"
+                          />
+                        </div>
+                        <div className="space-y-2">
+                          <Label htmlFor="upMbps">上行带宽 (Mbps)</Label>
+                          <Input
+                            id="upMbps"
+                            type="number"
+                            value={formData.upMbps}
+                            onChange={(e) => setFormData({ ...formData, upMbps: parseInt(e.target.value) || 0 })}
+                            placeholder="0 表示由服务端决定"
+                          />
+                        </div>
+                        <div className="space-y-2">
+                          <Label htmlFor="downMbps">下行带宽 (Mbps)</Label>
+                          <Input
+                            id="downMbps"
+                            type="number"
+                            value={formData.downMbps}
+                            onChange={(e) => setFormData({ ...formData, downMbps: parseInt(e.target.value) || 0 })}
+                            placeholder="0 表示由服务端决定"
+                          />
+                        </div>
+                      </>
+                    )}
+                    {formData.protocol === 'tuic' && (
+                      <>
+                        <div className="space-y-2">
+                          <Label htmlFor="congestionControl">拥塞控制</Label>
+                          <Select value={formData.congestionControl} onValueChange={(value) => setFormData({ ...formData, congestionControl: value })}>
+                            <SelectTrigger>
+                              <SelectValue />
+                            </SelectTrigger>
+                            <SelectContent>
+                              <SelectItem value="bbr">bbr</SelectItem>
+                              <SelectItem value="cubic">cubic</SelectItem>
+                              <SelectItem value="new_reno">new_reno</SelectItem>
+                            </SelectContent>
+                          </Select>
+                        </div>
+                        <div className="space-y-2">
+                          <Label htmlFor="udpRelayMode">UDP转发模式</Label>
+                          <Select value={formData.udpRelayMode} onValueChange={(value) => setFormData({ ...formData, udpRelayMode: value })}>
+                            <SelectTrigger>
+                              <SelectValue />
+                            </SelectTrigger>
+                            <SelectContent>
+                              <SelectItem value="native">native</SelectItem>
+                              <SelectItem value="quic">quic</SelectItem>
+                            </SelectContent>
+                          </Select>
+                        </div>
+                      </>
+                    )}
+                  </>
+                )}
+
+                {/* 完整配置：原样交给内核运行 */}
+                {formData.protocol === 'custom' && (
+                  <div className="space-y-2 md:col-span-2">
+                    <Label htmlFor="config">Xray / sing-box 完整配置 (JSON)</Label>
+                    <textarea
+                      id="config"
+                      value={formData.config}
+                      onChange={(e) => setFormData({ ...formData, config: e.target.value })}
+                      placeholder="粘贴完整的内核配置，未指定内核时根据配置格式自动识别"
+                      rows={12}
+                      spellCheck={false}
+                      className="flex w-full rounded-md border border-input bg-background px-3 py-2 font-mono text-xs ring-offset-background placeholder:text-muted-foreground focus-visible:outline-none focus-visible:ring-2 focus-visible:ring-ring focus-visible:ring-offset-2"
+                    />
+                  </div>
+                )}
+
                 {/* 通用TLS字段 */}
                 {(formData.protocol === 'vmess' || formData.protocol === 'vless') && (
                   <>
@@ -527,6 +856,8 @@ const ServersPage = () => {
              onDelete={handleDelete}
              onCopy={handleCopy}
              onConnect={handleConnect}
+             onSpeedTest={handleSpeedTest}
+             speedTesting={!!speedTesting[server.id]}
            />
         ))}
       </div>
@@ -6,10 +6,21 @@ import {
   RemoveServer, 
   ListServers, 
   ValidateServerName,
+  ImportServerLink,
   StartProxy,
   StopProxy,
   GetProxyStatus
 } from '../../wailsjs/go/main/App'
+import { EventsOn } from '../../wailsjs/runtime/runtime'
+
+// 后端推送的代理状态事件名
+const PROXY_STATUS_EVENT = 'proxy:status'
+// 后端推送的流量统计事件名
+const PROXY_TRAFFIC_EVENT = 'proxy:traffic'
+// 后端推送的流量配额提醒事件名
+const TRAFFIC_QUOTA_EVENT = 'traffic:quota'
+// 后端推送的订阅到期提醒事件名
+const SUBSCRIPTION_EXPIRY_EVENT = 'subscription:expiry'
 
 /**
  * 应用程序状态管理
@@ -28,7 +39,8 @@ const useAppStore = create(
       // 服务器状态
       servers: [], // 服务器列表
       activeServer: null, // 当前激活的服务器
-      proxyStatus: 'stopped', // 代理状态: 'running' | 'stopped' | 'connecting'
+      proxyStatus: 'stopped', // 代理状态: 'running' | 'stopped' | 'connecting' | 'error'
+      proxyError: '', // 最近一次代理错误
       
       // 系统状态
       systemStats: {
@@ -73,6 +85,19 @@ const useAppStore = create(
         }
       },
       
+      importServerLink: async (link) => {
+        try {
+          const server = await ImportServerLink(link)
+          // 重新加载服务器列表
+          const { loadServers } = get()
+          await loadServers()
+          return server
+        } catch (error) {
+          console.error('Failed to import server link:', error)
+          throw error
+        }
+      },
+      
       updateServer: async (server) => {
         try {
           await UpdateServer(server)
@@ -125,7 +150,7 @@ const useAppStore = create(
           return true
         } catch (error) {
           console.error('Failed to start proxy:', error)
-          set({ proxyStatus: 'stopped' })
+          set({ proxyStatus: 'error', proxyError: String(error) })
           throw error
         }
       },
@@ -143,15 +168,53 @@ const useAppStore = create(
       
       refreshProxyStatus: async () => {
         try {
-          const status = await GetProxyStatus()
-          set({ proxyStatus: status })
+          const info = await GetProxyStatus()
+          set({ proxyStatus: info.status, proxyError: info.lastError || '' })
         } catch (error) {
           console.error('Failed to refresh proxy status:', error)
         }
       },
+
+      // 订阅后端推送的状态变化，返回取消订阅函数
+      subscribeProxyStatus: () => {
+        return EventsOn(PROXY_STATUS_EVENT, (event) => {
+          const { servers, activeServer } = get()
+          const server = event.serverId
+            ? servers.find(s => s.id === event.serverId) || activeServer
+            : null
+          set({
+            proxyStatus: event.status,
+            activeServer: event.status === 'stopped' || event.status === 'error' ? null : server,
+            ...(event.error ? { proxyError: event.error } : {})
+          })
+        })
+      },
       
       // 系统状态更新
       updateSystemStats: (stats) => set({ systemStats: stats }),
+
+      // 订阅后端定时推送的上传/下载速度，返回取消订阅函数
+      subscribeTrafficStats: () => {
+        return EventsOn(PROXY_TRAFFIC_EVENT, (stats) => {
+          set((state) => ({
+            systemStats: {
+              ...state.systemStats,
+              upload: stats.upload,
+              download: stats.download
+            }
+          }))
+        })
+      },
+
+      // 订阅服务器本月流量超过配额的提醒，返回取消订阅函数
+      subscribeTrafficQuota: (onAlert) => {
+        return EventsOn(TRAFFIC_QUOTA_EVENT, onAlert)
+      },
+
+      // 订阅即将到期的提醒，返回取消订阅函数
+      subscribeSubscriptionExpiry: (onWarning) => {
+        return EventsOn(SUBSCRIPTION_EXPIRY_EVENT, onWarning)
+      },
       
       // 日志管理
       addLog: (log) => set((state) => ({
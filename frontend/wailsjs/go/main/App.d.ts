@@ -3,27 +3,91 @@
 import {server} from '../models';
 import {config} from '../models';
 import {proxy} from '../models';
+import {traffic} from '../models';
+import {subscription} from '../models';
+
+export function ActivateCoreVersion(arg1:string):Promise<proxy.CoreInfo>;
 
 export function AddServer(arg1:server.ServerConfig):Promise<void>;
 
+export function AddSubscription(arg1:string,arg2:string):Promise<subscription.Status>;
+
+export function CancelSpeedTest():Promise<void>;
+
+export function CloseConnection(arg1:string):Promise<void>;
+
+export function CloseConnectionsToHost(arg1:string):Promise<number>;
+
+export function ExportTrafficCSV(arg1:traffic.UsageQuery):Promise<string>;
+
+export function GetBlockListStatus():Promise<Array<proxy.BlockListStatus>>;
+
 export function GetConfig():Promise<config.Config>;
 
+export function GetCoreCapabilities():Promise<Array<proxy.CoreCapabilities>>;
+
+export function GetCoreInfo():Promise<proxy.CoreInfo>;
+
+export function GetCrashHistory():Promise<Array<proxy.CrashRecord>>;
+
+export function GetLANInfo():Promise<proxy.LANInfo>;
+
 export function GetLogLines(arg1:number):Promise<Array<string>>;
 
-export function GetProxyStatus():Promise<proxy.ProxyStatus>;
+export function GetPACURL():Promise<string>;
+
+export function GetProxyStatus():Promise<proxy.StatusInfo>;
 
 export function Greet(arg1:string):Promise<string>;
 
+export function ImportGFWList(arg1:string):Promise<proxy.GFWListImportResult>;
+
+export function ImportServerLink(arg1:string):Promise<server.ServerConfig>;
+
+export function InstallCoreVersion(arg1:string,arg2:string):Promise<proxy.CoreVersion>;
+
+export function ListConnections():Promise<Array<proxy.Connection>>;
+
+export function ListCoreVersions():Promise<Array<proxy.CoreVersion>>;
+
+export function ListRoutingRules():Promise<Array<config.RoutingRuleConfig>>;
+
 export function ListServers():Promise<Array<server.ServerConfig>>;
 
+export function ListSubscriptions():Promise<Array<subscription.Status>>;
+
+export function QueryAccessLog(arg1:proxy.AccessLogQuery):Promise<Array<proxy.AccessRecord>>;
+
+export function QueryTraffic(arg1:traffic.UsageQuery):Promise<Array<traffic.UsagePoint>>;
+
+export function RefreshBlockLists():Promise<Array<proxy.BlockListStatus>>;
+
+export function RefreshGFWList():Promise<proxy.GFWListImportResult>;
+
+export function RefreshSubscription(arg1:string):Promise<subscription.Status>;
+
+export function RegenerateLANCredentials():Promise<proxy.LANCredentials>;
+
 export function RemoveServer(arg1:string):Promise<void>;
 
+export function RemoveSubscription(arg1:string):Promise<void>;
+
+export function RollbackCoreVersion():Promise<proxy.CoreInfo>;
+
+export function SaveRoutingRules(arg1:Array<config.RoutingRuleConfig>):Promise<void>;
+
+export function SpeedTestServer(arg1:string):Promise<proxy.SpeedTestResult>;
+
 export function StartProxy(arg1:string):Promise<void>;
 
 export function StopProxy():Promise<void>;
 
+export function TestRoute(arg1:string):Promise<proxy.RouteTestResult>;
+
 export function UpdateConfig(arg1:config.Config):Promise<void>;
 
 export function UpdateServer(arg1:server.ServerConfig):Promise<void>;
 
+export function ValidateRoutingRule(arg1:config.RoutingRuleConfig):Promise<void>;
+
 export function ValidateServerName(arg1:string,arg2:string):Promise<void>;
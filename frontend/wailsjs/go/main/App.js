@@ -2,18 +2,66 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function ActivateCoreVersion(arg1) {
+  return window['go']['main']['App']['ActivateCoreVersion'](arg1);
+}
+
 export function AddServer(arg1) {
   return window['go']['main']['App']['AddServer'](arg1);
 }
 
+export function AddSubscription(arg1, arg2) {
+  return window['go']['main']['App']['AddSubscription'](arg1, arg2);
+}
+
+export function CancelSpeedTest() {
+  return window['go']['main']['App']['CancelSpeedTest']();
+}
+
+export function CloseConnection(arg1) {
+  return window['go']['main']['App']['CloseConnection'](arg1);
+}
+
+export function CloseConnectionsToHost(arg1) {
+  return window['go']['main']['App']['CloseConnectionsToHost'](arg1);
+}
+
+export function ExportTrafficCSV(arg1) {
+  return window['go']['main']['App']['ExportTrafficCSV'](arg1);
+}
+
+export function GetBlockListStatus() {
+  return window['go']['main']['App']['GetBlockListStatus']();
+}
+
 export function GetConfig() {
   return window['go']['main']['App']['GetConfig']();
 }
 
+export function GetCoreCapabilities() {
+  return window['go']['main']['App']['GetCoreCapabilities']();
+}
+
+export function GetCoreInfo() {
+  return window['go']['main']['App']['GetCoreInfo']();
+}
+
+export function GetCrashHistory() {
+  return window['go']['main']['App']['GetCrashHistory']();
+}
+
+export function GetLANInfo() {
+  return window['go']['main']['App']['GetLANInfo']();
+}
+
 export function GetLogLines(arg1) {
   return window['go']['main']['App']['GetLogLines'](arg1);
 }
 
+export function GetPACURL() {
+  return window['go']['main']['App']['GetPACURL']();
+}
+
 export function GetProxyStatus() {
   return window['go']['main']['App']['GetProxyStatus']();
 }
@@ -22,14 +70,82 @@ export function Greet(arg1) {
   return window['go']['main']['App']['Greet'](arg1);
 }
 
+export function ImportGFWList(arg1) {
+  return window['go']['main']['App']['ImportGFWList'](arg1);
+}
+
+export function ImportServerLink(arg1) {
+  return window['go']['main']['App']['ImportServerLink'](arg1);
+}
+
+export function InstallCoreVersion(arg1, arg2) {
+  return window['go']['main']['App']['InstallCoreVersion'](arg1, arg2);
+}
+
+export function ListConnections() {
+  return window['go']['main']['App']['ListConnections']();
+}
+
+export function ListCoreVersions() {
+  return window['go']['main']['App']['ListCoreVersions']();
+}
+
+export function ListRoutingRules() {
+  return window['go']['main']['App']['ListRoutingRules']();
+}
+
 export function ListServers() {
   return window['go']['main']['App']['ListServers']();
 }
 
+export function ListSubscriptions() {
+  return window['go']['main']['App']['ListSubscriptions']();
+}
+
+export function QueryAccessLog(arg1) {
+  return window['go']['main']['App']['QueryAccessLog'](arg1);
+}
+
+export function QueryTraffic(arg1) {
+  return window['go']['main']['App']['QueryTraffic'](arg1);
+}
+
+export function RefreshBlockLists() {
+  return window['go']['main']['App']['RefreshBlockLists']();
+}
+
+export function RefreshGFWList() {
+  return window['go']['main']['App']['RefreshGFWList']();
+}
+
+export function RefreshSubscription(arg1) {
+  return window['go']['main']['App']['RefreshSubscription'](arg1);
+}
+
+export function RegenerateLANCredentials() {
+  return window['go']['main']['App']['RegenerateLANCredentials']();
+}
+
 export function RemoveServer(arg1) {
   return window['go']['main']['App']['RemoveServer'](arg1);
 }
 
+export function RemoveSubscription(arg1) {
+  return window['go']['main']['App']['RemoveSubscription'](arg1);
+}
+
+export function RollbackCoreVersion() {
+  return window['go']['main']['App']['RollbackCoreVersion']();
+}
+
+export function SaveRoutingRules(arg1) {
+  return window['go']['main']['App']['SaveRoutingRules'](arg1);
+}
+
+export function SpeedTestServer(arg1) {
+  return window['go']['main']['App']['SpeedTestServer'](arg1);
+}
+
 export function StartProxy(arg1) {
   return window['go']['main']['App']['StartProxy'](arg1);
 }
@@ -38,6 +154,10 @@ export function StopProxy() {
   return window['go']['main']['App']['StopProxy']();
 }
 
+export function TestRoute(arg1) {
+  return window['go']['main']['App']['TestRoute'](arg1);
+}
+
 export function UpdateConfig(arg1) {
   return window['go']['main']['App']['UpdateConfig'](arg1);
 }
@@ -46,6 +166,10 @@ export function UpdateServer(arg1) {
   return window['go']['main']['App']['UpdateServer'](arg1);
 }
 
+export function ValidateRoutingRule(arg1) {
+  return window['go']['main']['App']['ValidateRoutingRule'](arg1);
+}
+
 export function ValidateServerName(arg1, arg2) {
   return window['go']['main']['App']['ValidateServerName'](arg1, arg2);
 }
@@ -138,6 +138,18 @@ export namespace server {
 	    host: string;
 	    tls: boolean;
 	    sni: string;
+	    alpn: string[];
+	    insecure: boolean;
+	    core: string;
+	    monthlyQuotaMB: number;
+	    subscriptionId: string;
+	    obfs: string;
+	    obfsPassword: string;
+	    upMbps: number;
+	    downMbps: number;
+	    congestionControl: string;
+	    udpRelayMode: string;
+	    config: string;
 	    // Go type: time
 	    created: any;
 	    // Go type: time
@@ -162,6 +174,18 @@ export namespace server {
 	        this.host = source["host"];
 	        this.tls = source["tls"];
 	        this.sni = source["sni"];
+	        this.alpn = source["alpn"];
+	        this.insecure = source["insecure"];
+	        this.core = source["core"];
+	        this.monthlyQuotaMB = source["monthlyQuotaMB"];
+	        this.subscriptionId = source["subscriptionId"];
+	        this.obfs = source["obfs"];
+	        this.obfsPassword = source["obfsPassword"];
+	        this.upMbps = source["upMbps"];
+	        this.downMbps = source["downMbps"];
+	        this.congestionControl = source["congestionControl"];
+	        this.udpRelayMode = source["udpRelayMode"];
+	        this.config = source["config"];
 	        this.created = this.convertValues(source["created"], null);
 	        this.updated = this.convertValues(source["updated"], null);
 	    }
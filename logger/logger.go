@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -17,6 +18,15 @@ var (
 	Logger *zap.Logger
 	// SugarLogger 全局Sugar日志实例
 	SugarLogger *zap.SugaredLogger
+	// CoreLogger Xray内核日志实例，同时写入应用日志和内核日志文件
+	CoreLogger *zap.SugaredLogger
+)
+
+const (
+	// ComponentKey 日志来源字段名
+	ComponentKey = "component"
+	// ComponentXray Xray内核日志来源
+	ComponentXray = "xray"
 )
 
 // InitLogger 初始化日志系统
@@ -25,6 +35,7 @@ func InitLogger(level string, enabled bool) error {
 		// 如果禁用日志，使用nop logger
 		Logger = zap.NewNop()
 		SugarLogger = Logger.Sugar()
+		CoreLogger = SugarLogger
 		return nil
 	}
 
@@ -50,13 +61,7 @@ func InitLogger(level string, enabled bool) error {
 	}
 
 	// 配置日志轮转
-	lumberjackLogger := &lumberjack.Logger{
-		Filename:   constants.GetLogFilePath(),
-		MaxSize:    10, // MB
-		MaxBackups: 5,
-		MaxAge:     30, // days
-		Compress:   true,
-	}
+	lumberjackLogger := newRotatingFile(constants.GetLogFilePath(), 5)
 
 	// 创建编码器配置
 	encoderConfig := zap.NewProductionEncoderConfig()
@@ -84,9 +89,36 @@ func InitLogger(level string, enabled bool) error {
 	Logger = zap.New(core, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel))
 	SugarLogger = Logger.Sugar()
 
+	// Xray内核日志单独轮转，并带上来源字段汇入应用日志
+	coreLumberjackLogger := newRotatingFile(constants.GetCoreLogFilePath(), 3)
+	CoreLogger = zap.New(zapcore.NewTee(
+		core,
+		zapcore.NewCore(
+			zapcore.NewJSONEncoder(encoderConfig),
+			zapcore.AddSync(coreLumberjackLogger),
+			zapLevel,
+		),
+	)).With(zap.String(ComponentKey, ComponentXray)).Sugar()
+
 	return nil
 }
 
+// newRotatingFile 创建按大小轮转的日志文件
+func newRotatingFile(filename string, maxBackups int) *lumberjack.Logger {
+	return &lumberjack.Logger{
+		Filename:   filename,
+		MaxSize:    10, // MB
+		MaxBackups: maxBackups,
+		MaxAge:     30, // days
+		Compress:   true,
+	}
+}
+
+// NewAccessLogWriter 创建Xray访问日志的写入器，与内核日志使用相同的轮转策略
+func NewAccessLogWriter() io.WriteCloser {
+	return newRotatingFile(constants.GetAccessLogFilePath(), 3)
+}
+
 // GetLogger 获取日志实例
 func GetLogger() *zap.Logger {
 	return Logger
@@ -97,11 +129,22 @@ func GetSugarLogger() *zap.SugaredLogger {
 	return SugarLogger
 }
 
+// GetCoreLogger 获取Xray内核日志实例，未初始化时返回空实现
+func GetCoreLogger() *zap.SugaredLogger {
+	if CoreLogger == nil {
+		return zap.NewNop().Sugar()
+	}
+	return CoreLogger
+}
+
 // Sync 同步日志
 func Sync() {
 	if Logger != nil {
 		Logger.Sync()
 	}
+	if CoreLogger != nil {
+		CoreLogger.Sync()
+	}
 }
 
 // ReadLogFile 读取日志文件内容
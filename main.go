@@ -28,6 +28,7 @@ func main() {
 		CSSDragProperty: "--wails-draggable",
 		CSSDragValue:    "drag",
 		OnStartup:       app.startup,
+		OnShutdown:      app.shutdown,
 		Bind: []interface{}{
 			app,
 		},
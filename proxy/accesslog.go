@@ -0,0 +1,302 @@
+package proxy
+
+import (
+	"Gox/config"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AccessRecord 连接记录，来自Xray访问日志
+type AccessRecord struct {
+	Time        time.Time `json:"time"`        // 连接时间
+	Source      string    `json:"source"`      // 来源地址
+	Network     string    `json:"network"`     // tcp, udp
+	Destination string    `json:"destination"` // 目标地址
+	Inbound     string    `json:"inbound"`     // 入站标签
+	Outbound    string    `json:"outbound"`    // 出站标签
+	Rule        string    `json:"rule"`        // 命中的路由规则，未命中任何规则时为空
+	Accepted    bool      `json:"accepted"`    // 是否被接受
+	Reason      string    `json:"reason"`      // 拒绝原因
+}
+
+// AccessLogQuery 连接记录查询条件
+type AccessLogQuery struct {
+	Destination string `json:"destination"` // 目标地址包含的内容，为空则不限制
+	Outbound    string `json:"outbound"`    // 出站标签，为空则不限制
+	Limit       int    `json:"limit"`       // 最多返回条数，0表示不限制
+}
+
+const (
+	// accessLogSpoolFile Xray写入访问日志的临时文件名，每次启动内核前清空
+	accessLogSpoolFile = "access.log"
+)
+
+var (
+	// accessLogPattern 匹配访问日志行：时间 [from] 来源 accepted|rejected 内容
+	accessLogPattern = regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}(?:\.\d+)?) (?:from )?(\S+) (accepted|rejected) +(.*)$`)
+	// accessTargetPattern 匹配accepted之后的内容：网络:目标 [入站 -> 出站] email: 用户
+	accessTargetPattern = regexp.MustCompile(`^(?:(tcp|udp):)?(\S+)(?: \[([^\]]*)\])?(?: email: (\S+))?`)
+)
+
+// parseAccessLogLine 解析一行访问日志
+func parseAccessLogLine(line string) (AccessRecord, bool) {
+	match := accessLogPattern.FindStringSubmatch(strings.TrimSpace(line))
+	if match == nil {
+		return AccessRecord{}, false
+	}
+
+	record := AccessRecord{
+		Source:   match[2],
+		Accepted: match[3] == "accepted",
+	}
+	if t, err := time.ParseInLocation("2006/01/02 15:04:05", match[1], time.Local); err == nil {
+		record.Time = t
+	}
+
+	if !record.Accepted {
+		record.Reason = strings.TrimSpace(match[4])
+		return record, true
+	}
+
+	target := accessTargetPattern.FindStringSubmatch(match[4])
+	if target == nil {
+		return AccessRecord{}, false
+	}
+	record.Network = target[1]
+	if record.Network == "" {
+		record.Network = "tcp"
+	}
+	record.Destination = target[2]
+
+	// 新版本使用 ">>"，旧版本使用 "->"，只有出站时不带入站标签
+	detour := target[3]
+	for _, sep := range []string{" -> ", " >> "} {
+		if idx := strings.Index(detour, sep); idx >= 0 {
+			record.Inbound = detour[:idx]
+			record.Outbound = detour[idx+len(sep):]
+			return record, true
+		}
+	}
+	record.Outbound = detour
+	return record, true
+}
+
+// matchAccessRule 在通往记录出站的规则中查找命中的规则
+func matchAccessRule(routing RoutingConfig, record AccessRecord) string {
+	host, portStr, err := net.SplitHostPort(record.Destination)
+	if err != nil {
+		return ""
+	}
+	port, _ := strconv.Atoi(portStr)
+
+	target := &RouteTestResult{Port: port}
+	if ip := net.ParseIP(host); ip != nil {
+		target.IP = ip.String()
+	} else {
+		target.Domain = strings.ToLower(host)
+	}
+
+	for _, rule := range routing.Rules {
+		if rule.OutboundTag != record.Outbound {
+			continue
+		}
+		if matched, _ := matchRule(rule, target); matched {
+			return describeRule(rule)
+		}
+	}
+	return ""
+}
+
+// AccessLogBuffer 固定容量的连接记录环形缓冲
+type AccessLogBuffer struct {
+	mu      sync.RWMutex
+	records []AccessRecord
+	next    int
+	full    bool
+}
+
+// NewAccessLogBuffer 创建连接记录缓冲
+func NewAccessLogBuffer(size int) *AccessLogBuffer {
+	if size <= 0 {
+		size = config.DefaultAccessLogRecords
+	}
+	return &AccessLogBuffer{records: make([]AccessRecord, size)}
+}
+
+// Resize 调整缓冲容量，保留最新的记录
+func (b *AccessLogBuffer) Resize(size int) {
+	if size <= 0 {
+		size = config.DefaultAccessLogRecords
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if size == len(b.records) {
+		return
+	}
+	ordered := b.orderedLocked()
+	if len(ordered) > size {
+		ordered = ordered[len(ordered)-size:]
+	}
+	b.records = make([]AccessRecord, size)
+	copy(b.records, ordered)
+	b.next = len(ordered) % size
+	b.full = len(ordered) == size
+}
+
+// Add 添加一条记录，缓冲已满时覆盖最旧的记录
+func (b *AccessLogBuffer) Add(record AccessRecord) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.records[b.next] = record
+	b.next = (b.next + 1) % len(b.records)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// Query 按条件查询记录，最新的记录在前
+func (b *AccessLogBuffer) Query(query AccessLogQuery) []AccessRecord {
+	b.mu.RLock()
+	ordered := b.orderedLocked()
+	b.mu.RUnlock()
+
+	destination := strings.ToLower(strings.TrimSpace(query.Destination))
+	result := []AccessRecord{}
+	for i := len(ordered) - 1; i >= 0; i-- {
+		record := ordered[i]
+		if destination != "" && !strings.Contains(strings.ToLower(record.Destination), destination) {
+			continue
+		}
+		if query.Outbound != "" && record.Outbound != query.Outbound {
+			continue
+		}
+		result = append(result, record)
+		if query.Limit > 0 && len(result) >= query.Limit {
+			break
+		}
+	}
+	return result
+}
+
+// orderedLocked 按时间顺序返回记录的副本（调用方需持有锁）
+func (b *AccessLogBuffer) orderedLocked() []AccessRecord {
+	if !b.full {
+		return append([]AccessRecord{}, b.records[:b.next]...)
+	}
+	ordered := make([]AccessRecord, 0, len(b.records))
+	ordered = append(ordered, b.records[b.next:]...)
+	return append(ordered, b.records[:b.next]...)
+}
+
+// QueryAccessLog 查询连接记录，用于确认请求是否经过代理
+func (m *XrayProxyManager) QueryAccessLog(query AccessLogQuery) []AccessRecord {
+	return m.accessLog.Query(query)
+}
+
+// accessLogPath Xray写入的访问日志路径，未启用时返回空字符串
+// Xray只写入工作目录下的临时文件，读取后转存到按大小轮转的访问日志中
+func (m *XrayProxyManager) accessLogPath(cfg config.AccessLogConfig) string {
+	if !cfg.Enabled {
+		return ""
+	}
+	return filepath.Join(m.workDir, accessLogSpoolFile)
+}
+
+// resetAccessLog 清空上次运行遗留的临时访问日志，需在Xray打开日志文件前调用
+func (m *XrayProxyManager) resetAccessLog(path string, cfg config.AccessLogConfig) {
+	if path == "" {
+		return
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		fmt.Printf("Failed to clear access log: %v\n", err)
+	}
+	m.accessLog.Resize(cfg.MaxRecords)
+}
+
+// followAccessLog 在进程运行期间持续解析访问日志新增的记录，并转存到轮转的访问日志文件
+// 进程退出后读完剩余的记录再关闭访问日志文件（调用方需持有锁）
+func (m *XrayProxyManager) followAccessLog(proc *coreProcess, xrayConfig *XrayConfig) {
+	path := xrayConfig.Log.Access
+	if path == "" {
+		return
+	}
+
+	routing := xrayConfig.Routing
+	done := make(chan struct{})
+	m.accessLogDone = done
+	go func() {
+		defer close(done)
+		followFile(path, proc.done, func(line string) {
+			if _, err := io.WriteString(m.accessLogFile, line+"\n"); err != nil {
+				fmt.Printf("Failed to write access log: %v\n", err)
+			}
+			record, ok := parseAccessLogLine(line)
+			if !ok {
+				return
+			}
+			if record.Accepted {
+				record.Rule = matchAccessRule(routing, record)
+			}
+			m.accessLog.Add(record)
+		})
+		if err := m.accessLogFile.Close(); err != nil {
+			fmt.Printf("Failed to close access log: %v\n", err)
+		}
+	}()
+}
+
+// followFile 持续读取文件新增的行，stop关闭后再读取一次剩余内容后返回；文件被截断时从头读取
+func followFile(path string, stop <-chan struct{}, onLine func(string)) {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	var offset int64
+	writer := &lineWriter{onLine: onLine}
+	for {
+		stopped := false
+		select {
+		case <-stop:
+			stopped = true
+		case <-ticker.C:
+		}
+
+		offset = readNewLines(path, offset, writer)
+		if stopped {
+			return
+		}
+	}
+}
+
+// readNewLines 从offset开始读取文件新增的内容，返回新的读取位置
+func readNewLines(path string, offset int64, writer *lineWriter) int64 {
+	file, err := os.Open(path)
+	if err != nil {
+		return offset
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || info.Size() == offset {
+		return offset
+	}
+	if info.Size() < offset {
+		offset = 0
+		writer.buf = nil
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err == nil {
+		n, _ := io.Copy(writer, file)
+		offset += n
+	}
+	return offset
+}
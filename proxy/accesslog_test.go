@@ -0,0 +1,25 @@
+package proxy
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestFollowFileDrainsOnStop(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	if err := os.WriteFile(path, []byte("first\nsecond\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 停止前写入的内容即使还没到轮询时间也要读完
+	stop := make(chan struct{})
+	close(stop)
+	var lines []string
+	followFile(path, stop, func(line string) { lines = append(lines, line) })
+
+	if want := []string{"first", "second"}; !slices.Equal(lines, want) {
+		t.Errorf("lines = %v, want %v", lines, want)
+	}
+}
@@ -0,0 +1,176 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+)
+
+// 内核二进制来源
+const (
+	CoreSourceConfigured = "configured" // 配置中指定的路径
+	CoreSourceEmbedded   = "embedded"   // 嵌入的二进制
+	CoreSourcePath       = "path"       // PATH中的同名程序
+)
+
+// CoreInfo 当前使用的内核信息
+type CoreInfo struct {
+	Path    string `json:"path"`    // 二进制路径
+	Version string `json:"version"` // 解析出的版本号
+	Source  string `json:"source"`  // configured, embedded, path
+}
+
+// binarySpec 内核二进制的查找规则
+type binarySpec struct {
+	name           string         // 可执行文件名（不含扩展名），同时用于在PATH中查找
+	versionPattern *regexp.Regexp // 解析 `<name> version` 输出的版本号
+	legacyNames    []string       // 兼容旧资源目录的嵌入文件名
+}
+
+// xraySpec Xray二进制查找规则
+var xraySpec = binarySpec{
+	name:           "xray",
+	versionPattern: xrayVersionPattern,
+	legacyNames:    []string{"xray.exe"},
+}
+
+// executableName 当前平台的可执行文件名
+func (s binarySpec) executableName() string {
+	if runtime.GOOS == "windows" {
+		return s.name + ".exe"
+	}
+	return s.name
+}
+
+// embeddedNames 当前平台可用的嵌入二进制文件名，按优先级排列
+func (s binarySpec) embeddedNames() []string {
+	name := fmt.Sprintf("%s-%s-%s", s.name, runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		// 兼容旧版本只打包 xray.exe 的资源目录
+		return append([]string{name + ".exe"}, s.legacyNames...)
+	}
+	return []string{name}
+}
+
+// resolveCoreBinary 按顺序确定内核路径：配置中指定的路径、当前平台的嵌入二进制、PATH中的同名程序
+// 每个候选都会运行 `<name> version` 校验，失败时继续尝试下一个
+func resolveCoreBinary(workDir string, resources embed.FS, configured string, spec binarySpec) (*CoreInfo, error) {
+	var errs []error
+
+	if configured != "" {
+		version, err := detectVersion(configured, spec.versionPattern)
+		if err == nil {
+			return &CoreInfo{Path: configured, Version: version, Source: CoreSourceConfigured}, nil
+		}
+		errs = append(errs, fmt.Errorf("configured binary %s: %w", configured, err))
+	}
+
+	if data, name, ok := embeddedBinary(resources, spec); ok {
+		target := filepath.Join(workDir, spec.executableName())
+		err := extractBinary(target, data)
+		if err == nil {
+			var version string
+			if version, err = detectVersion(target, spec.versionPattern); err == nil {
+				return &CoreInfo{Path: target, Version: version, Source: CoreSourceEmbedded}, nil
+			}
+		}
+		errs = append(errs, fmt.Errorf("embedded binary %s: %w", name, err))
+	}
+
+	if path, err := exec.LookPath(spec.name); err == nil {
+		version, err := detectVersion(path, spec.versionPattern)
+		if err == nil {
+			return &CoreInfo{Path: path, Version: version, Source: CoreSourcePath}, nil
+		}
+		errs = append(errs, fmt.Errorf("%s in PATH %s: %w", spec.name, path, err))
+	}
+
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no %s binary found for %s/%s: set a binary path or install %s in PATH",
+			spec.name, runtime.GOOS, runtime.GOARCH, spec.name)
+	}
+	return nil, fmt.Errorf("no usable %s binary found: %w", spec.name, errors.Join(errs...))
+}
+
+// embeddedBinary 读取当前平台的嵌入二进制
+func embeddedBinary(resources embed.FS, spec binarySpec) ([]byte, string, bool) {
+	for _, name := range spec.embeddedNames() {
+		if data, err := fs.ReadFile(resources, "resources/"+name); err == nil {
+			return data, name, true
+		}
+	}
+	return nil, "", false
+}
+
+// extractBinary 将嵌入的二进制写入指定路径，已有文件内容一致时跳过
+func extractBinary(targetPath string, data []byte) error {
+	if existing, err := os.ReadFile(targetPath); err == nil {
+		if sha256.Sum256(existing) == sha256.Sum256(data) {
+			return nil
+		}
+	}
+
+	// 确保目标目录存在
+	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// 先写临时文件再替换，避免中断时留下不完整的二进制
+	tmpPath := targetPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0755); err != nil {
+		return fmt.Errorf("failed to write core binary: %w", err)
+	}
+	if err := os.Rename(tmpPath, targetPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace core binary: %w", err)
+	}
+	return nil
+}
+
+// GetCoreInfo 获取Xray内核信息
+func (m *XrayProxyManager) GetCoreInfo() CoreInfo {
+	m.versionMu.Lock()
+	defer m.versionMu.Unlock()
+	return CoreInfo{
+		Path:    m.xrayPath,
+		Version: m.version,
+		Source:  m.coreSource,
+	}
+}
+
+// currentXray 获取当前使用的Xray，设置中的二进制路径变化后重新查找，无需重启应用
+func (m *XrayProxyManager) currentXray() (*CoreInfo, error) {
+	configured := m.proxyConfig().XrayBinaryPath
+	m.versionMu.Lock()
+	changed := configured != m.xrayConfigured
+	m.versionMu.Unlock()
+	if !changed {
+		info := m.GetCoreInfo()
+		return &info, nil
+	}
+
+	info, err := m.resolveActiveXray()
+	if err != nil {
+		return nil, err
+	}
+	m.useXray(info, configured)
+	fmt.Printf("Using xray %s from %s (%s)\n", info.Version, info.Path, info.Source)
+	return info, nil
+}
+
+// useXray 切换当前使用的Xray，configured为查找时设置中的二进制路径
+func (m *XrayProxyManager) useXray(info *CoreInfo, configured string) {
+	m.versionMu.Lock()
+	defer m.versionMu.Unlock()
+	m.xrayPath = info.Path
+	m.version = info.Version
+	m.coreSource = info.Source
+	m.xrayConfigured = configured
+}
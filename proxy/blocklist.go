@@ -0,0 +1,289 @@
+package proxy
+
+import (
+	"Gox/config"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// BlockListStatus 拦截列表状态
+type BlockListStatus struct {
+	ID      string `json:"id"`      // 列表ID
+	Name    string `json:"name"`    // 列表名称
+	Type    string `json:"type"`    // 列表类型
+	Source  string `json:"source"`  // 列表来源
+	Enabled bool   `json:"enabled"` // 是否启用
+	Count   int    `json:"count"`   // 编译后的规则条目数
+	Error   string `json:"error"`   // 加载错误
+}
+
+// blockListDir 远程拦截列表缓存目录
+func (m *XrayProxyManager) blockListDir() string {
+	return filepath.Join(m.workDir, "blocklists")
+}
+
+// blockListCachePath 远程拦截列表缓存文件路径
+func (m *XrayProxyManager) blockListCachePath(list config.BlockListConfig) string {
+	return filepath.Join(m.blockListDir(), sanitizeFileName(list.ID)+".txt")
+}
+
+// isRemoteSource 检查来源是否为URL
+func isRemoteSource(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// RefreshBlockLists 重新下载所有远程拦截列表并返回最新状态
+func (m *XrayProxyManager) RefreshBlockLists() ([]BlockListStatus, error) {
+	proxyCfg := m.proxyConfig()
+
+	if err := os.MkdirAll(m.blockListDir(), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create blocklist directory: %w", err)
+	}
+
+	// 单个列表下载失败不影响其他列表，错误体现在对应状态中
+	client := &http.Client{Timeout: 30 * time.Second}
+	failures := make(map[string]error)
+	for _, list := range proxyCfg.BlockLists {
+		if !list.Enabled || list.Type == config.BlockListTypeGeosite || !isRemoteSource(list.Source) {
+			continue
+		}
+		if err := downloadFile(client, list.Source, m.blockListCachePath(list)); err != nil {
+			failures[list.ID] = err
+		}
+	}
+
+	_, statuses := m.compileBlockLists(proxyCfg)
+	for i := range statuses {
+		if err, ok := failures[statuses[i].ID]; ok {
+			statuses[i].Error = err.Error()
+		}
+	}
+	return statuses, nil
+}
+
+// GetBlockListStatus 获取拦截列表状态
+func (m *XrayProxyManager) GetBlockListStatus() []BlockListStatus {
+	_, statuses := m.compileBlockLists(m.proxyConfig())
+	return statuses
+}
+
+// compileBlockLists 将拦截列表编译为路由规则
+// 白名单中的域名(及其子域名)会从列表中剔除，之后按正常分流规则处理
+// geosite分类无法剔除条目，由allowlistRules在拦截规则之前放行白名单域名
+func (m *XrayProxyManager) compileBlockLists(proxyCfg config.ProxyConfig) ([]RuleConfig, []BlockListStatus) {
+	var rules []RuleConfig
+	var statuses []BlockListStatus
+
+	for _, list := range proxyCfg.BlockLists {
+		status := BlockListStatus{
+			ID:      list.ID,
+			Name:    list.Name,
+			Type:    list.Type,
+			Source:  list.Source,
+			Enabled: list.Enabled,
+		}
+
+		var domains []string
+		if list.Type == config.BlockListTypeGeosite {
+			domains = []string{"geosite:" + strings.TrimPrefix(list.Source, "geosite:")}
+		} else {
+			entries, err := m.loadBlockList(list)
+			if err != nil {
+				status.Error = err.Error()
+			}
+			domains = filterAllowlisted(entries, proxyCfg.BlockAllowlist)
+		}
+		status.Count = len(domains)
+		statuses = append(statuses, status)
+
+		if !list.Enabled || len(domains) == 0 {
+			continue
+		}
+		rules = append(rules, RuleConfig{
+			Type:        "field",
+			RuleTag:     "block-" + list.ID,
+			OutboundTag: "block",
+			Domain:      domains,
+		})
+	}
+
+	return rules, statuses
+}
+
+// allowlistRules 启用了geosite拦截分类时，为白名单域名生成排在拦截规则之前的规则
+// 每个域名发往其余规则中第一条能在本地判定命中的规则的出站，都未命中时使用proxy
+func allowlistRules(proxyCfg config.ProxyConfig, blockRules, following []RuleConfig) []RuleConfig {
+	geosite := false
+	for _, rule := range blockRules {
+		for _, domain := range rule.Domain {
+			geosite = geosite || strings.HasPrefix(domain, "geosite:")
+		}
+	}
+	if !geosite {
+		return nil
+	}
+
+	var rules []RuleConfig
+	index := make(map[string]int)
+	for _, allowed := range proxyCfg.BlockAllowlist {
+		domain := strings.ToLower(strings.TrimSpace(allowed))
+		if domain == "" {
+			continue
+		}
+
+		outbound := "proxy"
+		for _, rule := range following {
+			if matched, _ := matchRule(rule, &RouteTestResult{Domain: domain}); matched {
+				outbound = rule.OutboundTag
+				break
+			}
+		}
+
+		i, ok := index[outbound]
+		if !ok {
+			i = len(rules)
+			index[outbound] = i
+			rules = append(rules, RuleConfig{
+				Type:        "field",
+				RuleTag:     "allowlist-" + outbound,
+				OutboundTag: outbound,
+			})
+		}
+		rules[i].Domain = append(rules[i].Domain, "domain:"+domain)
+	}
+	return rules
+}
+
+// loadBlockList 读取拦截列表内容，远程列表读取本地缓存
+func (m *XrayProxyManager) loadBlockList(list config.BlockListConfig) ([]string, error) {
+	path := list.Source
+	if isRemoteSource(list.Source) {
+		path = m.blockListCachePath(list)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && isRemoteSource(list.Source) {
+			return nil, fmt.Errorf("blocklist %s has not been downloaded yet", list.Name)
+		}
+		return nil, fmt.Errorf("failed to read blocklist %s: %w", list.Name, err)
+	}
+
+	return parseBlockList(data), nil
+}
+
+// parseBlockList 解析hosts格式或域名列表格式，返回Xray域名条目
+// hosts条目(如 0.0.0.0 ads.example.com)精确匹配，域名列表条目匹配子域名
+func parseBlockList(data []byte) []string {
+	var domains []string
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if idx := strings.IndexAny(line, "#!"); idx >= 0 {
+			line = strings.TrimSpace(line[:idx])
+		}
+		if line == "" {
+			continue
+		}
+
+		var entry string
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && net.ParseIP(fields[0]) != nil {
+			host := strings.ToLower(fields[1])
+			if host == "localhost" || host == "localhost.localdomain" || net.ParseIP(host) != nil {
+				continue
+			}
+			entry = "full:" + host
+		} else if strings.Contains(fields[0], ":") {
+			// 已带有Xray前缀(domain:/full:/regexp:/keyword:)的条目保持原样
+			entry = fields[0]
+		} else {
+			entry = "domain:" + strings.ToLower(strings.TrimPrefix(fields[0], "*."))
+		}
+
+		if !seen[entry] {
+			seen[entry] = true
+			domains = append(domains, entry)
+		}
+	}
+
+	return domains
+}
+
+// filterAllowlisted 剔除白名单中的域名及其子域名
+func filterAllowlisted(entries []string, allowlist []string) []string {
+	if len(allowlist) == 0 {
+		return entries
+	}
+
+	filtered := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if _, domain, ok := strings.Cut(entry, ":"); ok && isAllowlisted(domain, allowlist) {
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+	return filtered
+}
+
+// isAllowlisted 检查域名是否在白名单内
+func isAllowlisted(domain string, allowlist []string) bool {
+	for _, allowed := range allowlist {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed != "" && (domain == allowed || strings.HasSuffix(domain, "."+allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// downloadFile 下载URL内容到文件，先写临时文件再替换以免留下半截内容
+func downloadFile(client *http.Client, source, target string) error {
+	resp, err := client.Get(source)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", source, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: unexpected status %s", source, resp.Status)
+	}
+
+	tmpPath := target + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", tmpPath, err)
+	}
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to save %s: %w", source, err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to save %s: %w", source, err)
+	}
+
+	return os.Rename(tmpPath, target)
+}
+
+// sanitizeFileName 清理文件名中的非法字符
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>| `, r) {
+			return '_'
+		}
+		return r
+	}, name)
+}
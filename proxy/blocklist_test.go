@@ -0,0 +1,33 @@
+package proxy
+
+import (
+	"Gox/config"
+	"slices"
+	"testing"
+)
+
+func TestAllowlistRulesForGeosite(t *testing.T) {
+	proxyCfg := config.ProxyConfig{BlockAllowlist: []string{"ads.example.com", " Tracker.cn ", "other.org"}}
+	blockRules := []RuleConfig{{Type: "field", RuleTag: "block-ads", OutboundTag: "block", Domain: []string{"geosite:category-ads-all"}}}
+	following := []RuleConfig{
+		{Type: "field", OutboundTag: "direct", Domain: []string{"domain:tracker.cn"}},
+		{Type: "field", OutboundTag: "direct", IP: []string{"geoip:cn"}},
+	}
+
+	rules := allowlistRules(proxyCfg, blockRules, following)
+	if len(rules) != 2 {
+		t.Fatalf("got %d rules, want 2: %+v", len(rules), rules)
+	}
+	if rules[0].OutboundTag != "proxy" || !slices.Equal(rules[0].Domain, []string{"domain:ads.example.com", "domain:other.org"}) {
+		t.Errorf("unexpected proxy rule %+v", rules[0])
+	}
+	if rules[1].OutboundTag != "direct" || !slices.Equal(rules[1].Domain, []string{"domain:tracker.cn"}) {
+		t.Errorf("unexpected direct rule %+v", rules[1])
+	}
+
+	// 只有域名列表时白名单已从列表中剔除，不需要额外规则
+	listRules := []RuleConfig{{Type: "field", OutboundTag: "block", Domain: []string{"domain:ads.example.net"}}}
+	if rules := allowlistRules(proxyCfg, listRules, following); rules != nil {
+		t.Errorf("unexpected rules for domain lists: %+v", rules)
+	}
+}
@@ -0,0 +1,226 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// controllerConfig 连接管理API(Clash API)的地址和密钥
+type controllerConfig struct {
+	addr   string
+	secret string
+}
+
+// Connection 经过代理的活动连接
+type Connection struct {
+	ID          string    `json:"id"`          // 连接ID
+	Network     string    `json:"network"`     // tcp, udp
+	Source      string    `json:"source"`      // 来源地址
+	Destination string    `json:"destination"` // 目标地址，有域名时使用域名
+	Host        string    `json:"host"`        // 目标域名，未探测到时为空
+	Inbound     string    `json:"inbound"`     // 入站，如 mixed/mixed-in
+	Outbound    string    `json:"outbound"`    // 出站
+	Rule        string    `json:"rule"`        // 命中的路由规则
+	Process     string    `json:"process"`     // 发起连接的进程
+	Upload      int64     `json:"upload"`      // 已上传字节数
+	Download    int64     `json:"download"`    // 已下载字节数
+	Start       time.Time `json:"start"`       // 连接建立时间
+	Duration    int64     `json:"duration"`    // 已持续秒数
+}
+
+// clashConnections Clash API /connections 的响应
+type clashConnections struct {
+	Connections []struct {
+		ID       string `json:"id"`
+		Metadata struct {
+			Network         string `json:"network"`
+			Type            string `json:"type"`
+			SourceIP        string `json:"sourceIP"`
+			SourcePort      string `json:"sourcePort"`
+			DestinationIP   string `json:"destinationIP"`
+			DestinationPort string `json:"destinationPort"`
+			Host            string `json:"host"`
+			ProcessPath     string `json:"processPath"`
+		} `json:"metadata"`
+		Upload      int64     `json:"upload"`
+		Download    int64     `json:"download"`
+		Start       time.Time `json:"start"`
+		Chains      []string  `json:"chains"`
+		Rule        string    `json:"rule"`
+		RulePayload string    `json:"rulePayload"`
+	} `json:"connections"`
+}
+
+// ListConnections 列出经过代理的活动连接，需要内核支持连接管理
+func (m *XrayProxyManager) ListConnections() ([]Connection, error) {
+	controller, err := m.connectionController()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := controller.request(http.MethodGet, "/connections")
+	if err != nil {
+		return nil, err
+	}
+	var response clashConnections
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode connections: %w", err)
+	}
+
+	now := time.Now()
+	connections := make([]Connection, 0, len(response.Connections))
+	for _, item := range response.Connections {
+		meta := item.Metadata
+		destination := meta.Host
+		if destination == "" {
+			destination = meta.DestinationIP
+		}
+		connection := Connection{
+			ID:          item.ID,
+			Network:     meta.Network,
+			Source:      net.JoinHostPort(meta.SourceIP, meta.SourcePort),
+			Destination: net.JoinHostPort(destination, meta.DestinationPort),
+			Host:        meta.Host,
+			Inbound:     meta.Type,
+			Rule:        item.Rule,
+			Process:     meta.ProcessPath,
+			Upload:      item.Upload,
+			Download:    item.Download,
+			Start:       item.Start,
+		}
+		if !item.Start.IsZero() {
+			connection.Duration = int64(now.Sub(item.Start).Seconds())
+		}
+		// chains从最终出站开始排列
+		if len(item.Chains) > 0 {
+			connection.Outbound = item.Chains[0]
+		}
+		if connection.Rule == "" {
+			connection.Rule = item.RulePayload
+		}
+		connections = append(connections, connection)
+	}
+	return connections, nil
+}
+
+// CloseConnection 关闭指定连接
+func (m *XrayProxyManager) CloseConnection(id string) error {
+	controller, err := m.connectionController()
+	if err != nil {
+		return err
+	}
+	_, err = controller.request(http.MethodDelete, "/connections/"+url.PathEscape(id))
+	return err
+}
+
+// CloseConnectionsToHost 关闭所有发往指定域名或IP的连接，返回关闭的连接数
+func (m *XrayProxyManager) CloseConnectionsToHost(host string) (int, error) {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if host == "" {
+		return 0, fmt.Errorf("主机不能为空")
+	}
+
+	connections, err := m.ListConnections()
+	if err != nil {
+		return 0, err
+	}
+	closed := 0
+	for _, connection := range connections {
+		target, _, err := net.SplitHostPort(connection.Destination)
+		if err != nil {
+			target = connection.Destination
+		}
+		if strings.ToLower(target) != host && strings.ToLower(connection.Host) != host {
+			continue
+		}
+		if err := m.CloseConnection(connection.ID); err != nil {
+			return closed, err
+		}
+		closed++
+	}
+	return closed, nil
+}
+
+// connectionController 获取运行中内核的连接管理API，内核不支持时返回CapabilityError
+func (m *XrayProxyManager) connectionController() (*controllerConfig, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.proc == nil || m.activeCore == nil || m.runningConfig == nil {
+		return nil, fmt.Errorf("proxy is not running")
+	}
+	if m.runningConfig.controller == nil {
+		reason := fmt.Sprintf("%s core does not expose live connections", m.activeCore.Name())
+		if isCustomServer(m.activeServer) {
+			reason = "the custom config does not enable clash_api"
+		}
+		return nil, &CapabilityError{Feature: "connection tracking", Reason: reason}
+	}
+	return m.runningConfig.controller, nil
+}
+
+// request 调用连接管理API
+func (c *controllerConfig) request(method, path string) ([]byte, error) {
+	req, err := http.NewRequest(method, "http://"+c.addr+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create controller request: %w", err)
+	}
+	if c.secret != "" {
+		req.Header.Set("Authorization", "Bearer "+c.secret)
+	}
+
+	client := &http.Client{Timeout: apiTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call core controller: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read controller response: %w", err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("core controller %s %s failed: %s: %s", method, path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// controllerInboundTag 启动前检查端口时连接管理API使用的标签
+const controllerInboundTag = "controller"
+
+// newController 为内核生成只监听回环地址的连接管理API配置
+func newController(port int) (*controllerConfig, error) {
+	secret, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	return &controllerConfig{
+		addr:   net.JoinHostPort("127.0.0.1", strconv.Itoa(port)),
+		secret: secret,
+	}, nil
+}
+
+// listenPorts 内核启动后会监听的全部端口，包括连接管理API
+func listenPorts(xrayConfig *XrayConfig) []InboundConfig {
+	if xrayConfig.controller == nil {
+		return xrayConfig.Inbounds
+	}
+	host, portText, err := net.SplitHostPort(xrayConfig.controller.addr)
+	if err != nil {
+		return xrayConfig.Inbounds
+	}
+	port, _ := strconv.Atoi(portText)
+	return append(append([]InboundConfig{}, xrayConfig.Inbounds...), InboundConfig{
+		Tag:    controllerInboundTag,
+		Listen: host,
+		Port:   port,
+	})
+}
@@ -0,0 +1,29 @@
+package proxy
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestControllerPortChecked(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	controller, err := newController(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xrayConfig := &XrayConfig{controller: controller}
+
+	err = checkInboundPorts(listenPorts(xrayConfig), 100*time.Millisecond)
+	var inUse *PortInUseError
+	if !errors.As(err, &inUse) || inUse.Inbound != controllerInboundTag || inUse.Port != port {
+		t.Errorf("expected the controller port to be reported in use, got %v", err)
+	}
+}
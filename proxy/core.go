@@ -0,0 +1,154 @@
+package proxy
+
+import (
+	"Gox/config"
+	"Gox/server"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// configTestTimeout 检查内核配置文件的超时时间
+const configTestTimeout = 10 * time.Second
+
+// 内核可选功能
+const (
+	FeatureFakeDNS     = "fakedns"      // FakeDNS
+	FeatureHotSwitch   = "hot-switch"   // 运行中通过API切换服务器
+	FeatureAccessLog   = "access-log"   // 访问日志
+	FeatureProcessRule = "process-rule" // 按进程分流
+	FeatureStats       = "stats"        // 通过API查询流量统计
+	FeatureConnections = "connections"  // 查看和关闭活动连接
+)
+
+// CoreCapabilities 内核支持的协议、传输和功能，前端据此禁用无法使用的组合
+type CoreCapabilities struct {
+	Core       string   `json:"core"`       // 内核名称
+	Available  bool     `json:"available"`  // 是否找到可用的二进制
+	Version    string   `json:"version"`    // 内核版本
+	Error      string   `json:"error"`      // 不可用的原因
+	Protocols  []string `json:"protocols"`  // 支持的出站协议
+	Transports []string `json:"transports"` // 支持的传输方式
+	Features   []string `json:"features"`   // 支持的可选功能
+}
+
+// supports 检查内核是否支持指定协议和传输方式
+func (c CoreCapabilities) supports(protocol, network string) bool {
+	if !containsString(c.Protocols, protocol) {
+		return false
+	}
+	// Hysteria2和TUIC基于QUIC，不使用传输层配置
+	if protocol == "hysteria2" || protocol == "tuic" {
+		return true
+	}
+	if network == "" {
+		network = "tcp"
+	}
+	return containsString(c.Transports, network)
+}
+
+// hasFeature 检查内核是否支持指定功能
+func (c CoreCapabilities) hasFeature(feature string) bool {
+	return containsString(c.Features, feature)
+}
+
+// Core 代理内核，负责生成自身格式的配置文件并启动进程
+// 配置统一由XrayConfig描述，各内核再转换为自己的格式
+type Core interface {
+	// Name 内核名称
+	Name() string
+	// Binary 查找内核二进制
+	Binary() (*CoreInfo, error)
+	// Capabilities 内核能力
+	Capabilities() CoreCapabilities
+	// GenerateConfig 生成内核配置文件内容
+	GenerateConfig(model *XrayConfig, srv *server.ServerConfig) ([]byte, error)
+	// Command 生成启动命令
+	Command(ctx context.Context, binary, configPath string) *exec.Cmd
+	// TestConfig 检查配置文件是否有效
+	TestConfig(binary, configPath string) error
+	// IsReadyLine 判断一行输出是否表示内核已启动完成
+	IsReadyLine(line string) bool
+}
+
+// runConfigTest 运行内核的配置检查命令，失败时返回输出的最后一行
+func runConfigTest(name, binary string, args ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), configTestTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, binary, args...)
+	hideWindow(cmd)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		detail := strings.TrimSpace(string(output))
+		if idx := strings.LastIndex(detail, "\n"); idx >= 0 {
+			detail = detail[idx+1:]
+		}
+		return fmt.Errorf("%s config test failed: %s", name, detail)
+	}
+	return nil
+}
+
+// GetCoreCapabilities 获取所有内核的能力
+func (m *XrayProxyManager) GetCoreCapabilities() []CoreCapabilities {
+	capabilities := make([]CoreCapabilities, 0, len(m.coreOrder))
+	for _, name := range m.coreOrder {
+		capabilities = append(capabilities, m.cores[name].Capabilities())
+	}
+	return capabilities
+}
+
+// selectCore 选择服务器使用的内核：服务器指定的内核优先，其次是全局设置，默认Xray
+// 服务器未指定内核且全局内核不支持其协议时，改用第一个支持的可用内核
+// 内核不可用或不支持服务器的协议、传输方式及已启用的功能时返回CapabilityError
+func (m *XrayProxyManager) selectCore(srv *server.ServerConfig, proxyCfg config.ProxyConfig) (Core, error) {
+	if isCustomServer(srv) {
+		return m.selectCustomCore(srv, proxyCfg)
+	}
+
+	name := srv.Core
+	if name == "" {
+		name = proxyCfg.Core
+		if name == "" {
+			name = config.CoreXray
+		}
+		if core, ok := m.cores[name]; ok && !core.Capabilities().supports(srv.Protocol, srv.Network) {
+			for _, candidate := range m.coreOrder {
+				caps := m.cores[candidate].Capabilities()
+				if caps.Available && caps.supports(srv.Protocol, srv.Network) {
+					name = candidate
+					break
+				}
+			}
+		}
+	}
+
+	core, ok := m.cores[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown core %s", name)
+	}
+
+	caps := core.Capabilities()
+	if !caps.Available {
+		return nil, &CapabilityError{Feature: name, Reason: caps.Error}
+	}
+	if !caps.supports(srv.Protocol, srv.Network) {
+		network := srv.Network
+		if network == "" {
+			network = "tcp"
+		}
+		return nil, &CapabilityError{
+			Feature: fmt.Sprintf("%s over %s", srv.Protocol, network),
+			Reason:  fmt.Sprintf("%s core does not support it", name),
+		}
+	}
+	if proxyCfg.FakeDNS.Enabled && !caps.hasFeature(FeatureFakeDNS) {
+		return nil, &CapabilityError{
+			Feature: "fakedns",
+			Reason:  fmt.Sprintf("%s core does not support it", name),
+		}
+	}
+	return core, nil
+}
@@ -0,0 +1,92 @@
+package proxy
+
+import (
+	"Gox/logger"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// xrayLogPattern 匹配Xray日志行：日期 时间 [级别] 内容，访问日志没有级别
+var xrayLogPattern = regexp.MustCompile(`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}(?:\.\d+)? (?:\[(\w+)\] )?(.*)$`)
+
+// singBoxLogPattern 匹配sing-box日志行：可选的时区和时间戳，之后是大写级别和内容
+var singBoxLogPattern = regexp.MustCompile(`^(?:[+-]\d{4} \d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2} )?(TRACE|DEBUG|INFO|WARN|ERROR|FATAL|PANIC) (.*)$`)
+
+// ansiPattern 匹配终端颜色控制符
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// parseXrayLogLine 解析Xray日志行，返回日志级别和去掉时间戳后的内容
+// 无法识别级别的行使用fallback级别
+func parseXrayLogLine(line string, fallback zapcore.Level) (zapcore.Level, string) {
+	match := xrayLogPattern.FindStringSubmatch(line)
+	if match == nil {
+		return fallback, line
+	}
+
+	message := match[2]
+	switch strings.ToLower(match[1]) {
+	case "debug":
+		return zapcore.DebugLevel, message
+	case "info":
+		return zapcore.InfoLevel, message
+	case "warning":
+		return zapcore.WarnLevel, message
+	case "error":
+		return zapcore.ErrorLevel, message
+	case "":
+		// 访问日志
+		return zapcore.InfoLevel, message
+	default:
+		return fallback, match[1] + " " + message
+	}
+}
+
+// parseSingBoxLogLine 解析sing-box日志行，返回日志级别、内容以及是否识别
+func parseSingBoxLogLine(line string) (zapcore.Level, string, bool) {
+	match := singBoxLogPattern.FindStringSubmatch(line)
+	if match == nil {
+		return zapcore.InfoLevel, line, false
+	}
+
+	switch match[1] {
+	case "TRACE", "DEBUG":
+		return zapcore.DebugLevel, match[2], true
+	case "INFO":
+		return zapcore.InfoLevel, match[2], true
+	case "WARN":
+		return zapcore.WarnLevel, match[2], true
+	default:
+		return zapcore.ErrorLevel, match[2], true
+	}
+}
+
+// parseCoreLogLine 解析内核日志行，依次尝试sing-box和Xray格式
+func parseCoreLogLine(line string, fallback zapcore.Level) (zapcore.Level, string) {
+	line = ansiPattern.ReplaceAllString(line, "")
+	if level, message, ok := parseSingBoxLogLine(line); ok {
+		return level, message
+	}
+	return parseXrayLogLine(line, fallback)
+}
+
+// logCoreLine 将一行内核输出写入内核日志
+func logCoreLine(line string, fallback zapcore.Level) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+
+	level, message := parseCoreLogLine(line, fallback)
+	coreLogger := logger.GetCoreLogger()
+	switch level {
+	case zapcore.DebugLevel:
+		coreLogger.Debug(message)
+	case zapcore.InfoLevel:
+		coreLogger.Info(message)
+	case zapcore.WarnLevel:
+		coreLogger.Warn(message)
+	default:
+		coreLogger.Error(message)
+	}
+}
@@ -0,0 +1,368 @@
+package proxy
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// coresDirName 已安装内核版本的目录名
+	coresDirName = "cores"
+	// coreStateFileName 记录当前版本和上一个版本的文件名
+	coreStateFileName = "active.json"
+	// coreDownloadTimeout 下载内核发布包的超时时间
+	coreDownloadTimeout = 5 * time.Minute
+)
+
+// CoreSourceManaged 通过版本管理安装的内核
+const CoreSourceManaged = "managed"
+
+// coreVersionsMu 保护版本目录和状态文件
+var coreVersionsMu sync.Mutex
+
+// sha256Pattern 匹配十六进制SHA256值
+var sha256Pattern = regexp.MustCompile(`(?i)\b[0-9a-f]{64}\b`)
+
+// CoreVersion 已安装的Xray版本
+type CoreVersion struct {
+	Version     string    `json:"version"`     // 版本号
+	Path        string    `json:"path"`        // 二进制路径
+	InstalledAt time.Time `json:"installedAt"` // 安装时间
+	Active      bool      `json:"active"`      // 是否为当前使用的版本
+}
+
+// coreVersionState 版本切换状态，Previous为nil表示没有可回滚的版本，空字符串表示默认内核
+type coreVersionState struct {
+	Active   string  `json:"active"`
+	Previous *string `json:"previous,omitempty"`
+}
+
+// coresDir 版本目录
+func (m *XrayProxyManager) coresDir() string {
+	return filepath.Join(m.workDir, coresDirName)
+}
+
+// coreVersionBinary 指定版本的二进制路径
+func (m *XrayProxyManager) coreVersionBinary(version string) string {
+	return filepath.Join(m.coresDir(), version, xraySpec.executableName())
+}
+
+// loadCoreVersionState 读取版本状态，文件不存在时返回空状态
+func (m *XrayProxyManager) loadCoreVersionState() (coreVersionState, error) {
+	var state coreVersionState
+	data, err := os.ReadFile(filepath.Join(m.coresDir(), coreStateFileName))
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("failed to read core version state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("failed to parse core version state: %w", err)
+	}
+	return state, nil
+}
+
+// saveCoreVersionState 保存版本状态
+func (m *XrayProxyManager) saveCoreVersionState(state coreVersionState) error {
+	if err := os.MkdirAll(m.coresDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create cores directory: %w", err)
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal core version state: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(m.coresDir(), coreStateFileName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write core version state: %w", err)
+	}
+	return nil
+}
+
+// resolveActiveXray 确定启动时使用的Xray：已选择的管理版本优先，不可用时回退到默认查找规则
+func (m *XrayProxyManager) resolveActiveXray() (*CoreInfo, error) {
+	state, err := m.loadCoreVersionState()
+	if err != nil {
+		fmt.Printf("Ignoring core version state: %v\n", err)
+	}
+	if state.Active != "" {
+		info, err := m.managedCoreInfo(state.Active)
+		if err == nil {
+			return info, nil
+		}
+		fmt.Printf("Xray %s is not usable, falling back: %v\n", state.Active, err)
+	}
+	return resolveCoreBinary(m.workDir, m.coreBinaries, m.proxyConfig().XrayBinaryPath, xraySpec)
+}
+
+// managedCoreInfo 校验已安装的版本
+func (m *XrayProxyManager) managedCoreInfo(version string) (*CoreInfo, error) {
+	path := m.coreVersionBinary(version)
+	detected, err := detectVersion(path, xrayVersionPattern)
+	if err != nil {
+		return nil, err
+	}
+	if detected != version {
+		return nil, fmt.Errorf("binary in %s reports version %s", filepath.Dir(path), detected)
+	}
+	return &CoreInfo{Path: path, Version: version, Source: CoreSourceManaged}, nil
+}
+
+// ListCoreVersions 列出已安装的Xray版本，按版本号从新到旧排列
+func (m *XrayProxyManager) ListCoreVersions() ([]CoreVersion, error) {
+	coreVersionsMu.Lock()
+	defer coreVersionsMu.Unlock()
+
+	entries, err := os.ReadDir(m.coresDir())
+	if os.IsNotExist(err) {
+		return []CoreVersion{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cores directory: %w", err)
+	}
+
+	state, _ := m.loadCoreVersionState()
+	versions := []CoreVersion{}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		binary := m.coreVersionBinary(entry.Name())
+		stat, err := os.Stat(binary)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, CoreVersion{
+			Version:     entry.Name(),
+			Path:        binary,
+			InstalledAt: stat.ModTime(),
+			Active:      entry.Name() == state.Active,
+		})
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return compareVersions(versions[i].Version, versions[j].Version) > 0
+	})
+	return versions, nil
+}
+
+// InstallCoreVersion 从本地文件或URL安装Xray发布包(zip)
+// checksum为空时读取发布包旁的 .dgst 文件；校验失败或找不到校验值时拒绝安装
+func (m *XrayProxyManager) InstallCoreVersion(source, checksum string) (*CoreVersion, error) {
+	data, err := fetchCoreFile(source)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(checksum), "sha256:"))
+	if expected == "" {
+		digest, err := fetchCoreFile(source + ".dgst")
+		if err != nil {
+			return nil, fmt.Errorf("no checksum given and digest file is unavailable: %w", err)
+		}
+		if expected = parseDigest(digest); expected == "" {
+			return nil, fmt.Errorf("no sha256 checksum found in %s.dgst", source)
+		}
+	}
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return nil, fmt.Errorf("checksum mismatch: expected %s, got %s", expected, actual)
+	}
+
+	coreVersionsMu.Lock()
+	defer coreVersionsMu.Unlock()
+
+	if err := os.MkdirAll(m.coresDir(), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cores directory: %w", err)
+	}
+	tmpDir, err := os.MkdirTemp(m.coresDir(), ".install-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create install directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := extractCoreArchive(data, tmpDir); err != nil {
+		return nil, err
+	}
+	version, err := detectVersion(filepath.Join(tmpDir, xraySpec.executableName()), xrayVersionPattern)
+	if err != nil {
+		return nil, err
+	}
+
+	state, _ := m.loadCoreVersionState()
+	target := filepath.Join(m.coresDir(), version)
+	if _, err := os.Stat(target); err == nil {
+		if version == state.Active {
+			return nil, fmt.Errorf("xray %s is the active version and cannot be replaced", version)
+		}
+		if err := os.RemoveAll(target); err != nil {
+			return nil, fmt.Errorf("failed to remove existing xray %s: %w", version, err)
+		}
+	}
+	if err := os.Rename(tmpDir, target); err != nil {
+		return nil, fmt.Errorf("failed to install xray %s: %w", version, err)
+	}
+
+	return &CoreVersion{
+		Version:     version,
+		Path:        m.coreVersionBinary(version),
+		InstalledAt: time.Now(),
+		Active:      false,
+	}, nil
+}
+
+// ActivateCoreVersion 切换当前使用的Xray版本，空字符串表示恢复默认内核，下次启动代理时生效
+func (m *XrayProxyManager) ActivateCoreVersion(version string) (CoreInfo, error) {
+	coreVersionsMu.Lock()
+	defer coreVersionsMu.Unlock()
+
+	state, err := m.loadCoreVersionState()
+	if err != nil {
+		return CoreInfo{}, err
+	}
+	return m.activateCoreVersionLocked(state, version)
+}
+
+// RollbackCoreVersion 切换回上一个使用的Xray版本
+func (m *XrayProxyManager) RollbackCoreVersion() (CoreInfo, error) {
+	coreVersionsMu.Lock()
+	defer coreVersionsMu.Unlock()
+
+	state, err := m.loadCoreVersionState()
+	if err != nil {
+		return CoreInfo{}, err
+	}
+	if state.Previous == nil {
+		return CoreInfo{}, fmt.Errorf("no previous xray version to roll back to")
+	}
+	return m.activateCoreVersionLocked(state, *state.Previous)
+}
+
+// activateCoreVersionLocked 校验并切换版本，同时记录切换前的版本（调用方需持有coreVersionsMu）
+func (m *XrayProxyManager) activateCoreVersionLocked(state coreVersionState, version string) (CoreInfo, error) {
+	var info *CoreInfo
+	var err error
+	configured := m.proxyConfig().XrayBinaryPath
+	if version == "" {
+		info, err = resolveCoreBinary(m.workDir, m.coreBinaries, configured, xraySpec)
+	} else {
+		info, err = m.managedCoreInfo(version)
+	}
+	if err != nil {
+		return CoreInfo{}, err
+	}
+
+	if version != state.Active {
+		previous := state.Active
+		state.Previous = &previous
+		state.Active = version
+		if err := m.saveCoreVersionState(state); err != nil {
+			return CoreInfo{}, err
+		}
+	}
+
+	m.useXray(info, configured)
+
+	fmt.Printf("Switched to xray %s from %s (%s)\n", info.Version, info.Path, info.Source)
+	return *info, nil
+}
+
+// fetchCoreFile 读取本地文件或下载URL内容
+func fetchCoreFile(source string) ([]byte, error) {
+	if !isRemoteSource(source) {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", source, err)
+		}
+		return data, nil
+	}
+
+	client := &http.Client{Timeout: coreDownloadTimeout}
+	resp, err := client.Get(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", source, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: unexpected status %s", source, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// parseDigest 从 .dgst 文件中取出SHA256值，兼容 "SHA2-256= <hex>" 和 "<hex>  file" 两种格式
+func parseDigest(data []byte) string {
+	for _, line := range strings.Split(string(data), "\n") {
+		// 带算法标签的行只接受SHA256，SHA3-256等长度相同的校验值需要跳过
+		if label, _, ok := strings.Cut(line, "="); ok {
+			label = strings.ToUpper(strings.TrimSpace(label))
+			if label != "SHA2-256" && label != "SHA256" {
+				continue
+			}
+		}
+		if match := sha256Pattern.FindString(line); match != "" {
+			return strings.ToLower(match)
+		}
+	}
+	return ""
+}
+
+// extractCoreArchive 解压发布包中的顶层文件，发布包中必须包含当前平台的Xray可执行文件
+func extractCoreArchive(data []byte, targetDir string) error {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("failed to open xray archive: %w", err)
+	}
+
+	found := false
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		// 只使用文件名，避免压缩包中的路径跳出目标目录
+		name := filepath.Base(file.Name)
+		if name == "." || name == ".." || strings.HasPrefix(name, ".") {
+			continue
+		}
+		if err := extractZipFile(file, filepath.Join(targetDir, name)); err != nil {
+			return err
+		}
+		if name == xraySpec.executableName() {
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("archive does not contain %s", xraySpec.executableName())
+	}
+	return nil
+}
+
+// extractZipFile 解压单个文件
+func extractZipFile(file *zip.File, target string) error {
+	src, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s in archive: %w", file.Name, err)
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", target, err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return fmt.Errorf("failed to extract %s: %w", file.Name, err)
+	}
+	return dst.Close()
+}
@@ -0,0 +1,181 @@
+package proxy
+
+import (
+	"Gox/config"
+	"Gox/server"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// isCustomServer 是否为使用完整内核配置的服务器
+func isCustomServer(srv *server.ServerConfig) bool {
+	return srv != nil && srv.Protocol == server.ProtocolCustom
+}
+
+// customDocument 完整配置中用于识别内核和入站的部分
+type customDocument struct {
+	Inbounds []struct {
+		Tag        string          `json:"tag"`
+		Protocol   string          `json:"protocol"` // Xray
+		Type       string          `json:"type"`     // sing-box
+		Listen     string          `json:"listen"`
+		Port       json.RawMessage `json:"port"`        // Xray，可以是数字或字符串
+		ListenPort int             `json:"listen_port"` // sing-box
+	} `json:"inbounds"`
+	Outbounds []struct {
+		Protocol string `json:"protocol"`
+		Type     string `json:"type"`
+	} `json:"outbounds"`
+	Experimental struct {
+		ClashAPI struct {
+			ExternalController string `json:"external_controller"`
+			Secret             string `json:"secret"`
+		} `json:"clash_api"`
+	} `json:"experimental"` // sing-box
+}
+
+// parseCustomConfig 解析完整配置
+func parseCustomConfig(data string) (*customDocument, error) {
+	if strings.TrimSpace(data) == "" {
+		return nil, fmt.Errorf("完整配置不能为空")
+	}
+	var document customDocument
+	if err := json.Unmarshal([]byte(data), &document); err != nil {
+		return nil, fmt.Errorf("完整配置不是有效的JSON: %w", err)
+	}
+	return &document, nil
+}
+
+// detectCore 根据配置结构判断内核：sing-box的入站和出站使用type字段，Xray使用protocol字段
+func (d *customDocument) detectCore() string {
+	for _, inbound := range d.Inbounds {
+		if inbound.Type != "" {
+			return config.CoreSingBox
+		}
+		if inbound.Protocol != "" {
+			return config.CoreXray
+		}
+	}
+	for _, outbound := range d.Outbounds {
+		if outbound.Type != "" {
+			return config.CoreSingBox
+		}
+		if outbound.Protocol != "" {
+			return config.CoreXray
+		}
+	}
+	return ""
+}
+
+// inbounds 识别配置中监听本地端口的入站，未指定监听地址时使用内核的默认值
+func (d *customDocument) inbounds(core string) []InboundConfig {
+	var inbounds []InboundConfig
+	for _, inbound := range d.Inbounds {
+		result := InboundConfig{Tag: inbound.Tag, Listen: inbound.Listen}
+		if core == config.CoreSingBox {
+			result.Protocol = inbound.Type
+			result.Port = inbound.ListenPort
+			if result.Listen == "" {
+				result.Listen = "127.0.0.1"
+			}
+		} else {
+			result.Protocol = inbound.Protocol
+			result.Port = customPort(inbound.Port)
+			if result.Listen == "" {
+				result.Listen = "0.0.0.0"
+			}
+		}
+		// 没有端口的入站（如tun）和端口范围不参与端口检查
+		if result.Port <= 0 || result.Port > 65535 {
+			continue
+		}
+		inbounds = append(inbounds, result)
+	}
+	return inbounds
+}
+
+// controller 配置中启用的sing-box Clash API，未启用时返回nil
+func (d *customDocument) controller() *controllerConfig {
+	clashAPI := d.Experimental.ClashAPI
+	host, port, err := net.SplitHostPort(clashAPI.ExternalController)
+	if err != nil {
+		return nil
+	}
+	return &controllerConfig{
+		addr:   net.JoinHostPort(pacProxyHost(host), port),
+		secret: clashAPI.Secret,
+	}
+}
+
+// customPort 解析Xray入站端口，端口范围等无法确定单个端口的写法返回0
+func customPort(raw json.RawMessage) int {
+	var port int
+	if err := json.Unmarshal(raw, &port); err == nil {
+		return port
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		if port, err := strconv.Atoi(strings.TrimSpace(text)); err == nil {
+			return port
+		}
+	}
+	return 0
+}
+
+// selectCustomCore 选择运行完整配置的内核：服务器指定的内核优先，其次根据配置结构判断，最后使用全局设置
+// 完整配置由内核自行校验，因此只检查内核是否可用
+func (m *XrayProxyManager) selectCustomCore(srv *server.ServerConfig, proxyCfg config.ProxyConfig) (Core, error) {
+	name := srv.Core
+	if name == "" {
+		document, err := parseCustomConfig(srv.Config)
+		if err != nil {
+			return nil, err
+		}
+		name = document.detectCore()
+	}
+	if name == "" {
+		name = proxyCfg.Core
+	}
+	if name == "" {
+		name = config.CoreXray
+	}
+
+	core, ok := m.cores[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown core %s", name)
+	}
+	if caps := core.Capabilities(); !caps.Available {
+		return nil, &CapabilityError{Feature: name, Reason: caps.Error}
+	}
+	return core, nil
+}
+
+// prepareCustomConfig 将完整配置原样写入配置文件，用内核检查后识别其入站端口
+func (m *XrayProxyManager) prepareCustomConfig(core Core, binary string, srv *server.ServerConfig) (*XrayConfig, error) {
+	document, err := parseCustomConfig(srv.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(m.configPath, []byte(srv.Config), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write %s config file: %w", core.Name(), err)
+	}
+	if err := core.TestConfig(binary, m.configPath); err != nil {
+		return nil, err
+	}
+
+	inbounds := document.inbounds(core.Name())
+	if err := checkInboundPorts(inbounds, time.Second); err != nil {
+		return nil, err
+	}
+	xrayConfig := &XrayConfig{Inbounds: inbounds, raw: []byte(srv.Config)}
+	if core.Capabilities().hasFeature(FeatureConnections) {
+		xrayConfig.controller = document.controller()
+	}
+	return xrayConfig, nil
+}
@@ -0,0 +1,25 @@
+package proxy
+
+import "fmt"
+
+// CapabilityError 当前内核或平台不支持某项功能
+type CapabilityError struct {
+	Feature string // 功能名称
+	Reason  string // 不支持的原因
+}
+
+// Error 实现error接口
+func (e *CapabilityError) Error() string {
+	return fmt.Sprintf("%s is not supported: %s", e.Feature, e.Reason)
+}
+
+// ConfigMergeError 自定义Xray配置片段无法合并到生成的配置中
+type ConfigMergeError struct {
+	Path   string // 冲突位置，如 $.routing.rules[1]
+	Reason string // 冲突原因
+}
+
+// Error 实现error接口
+func (e *ConfigMergeError) Error() string {
+	return fmt.Sprintf("cannot merge custom xray config at %s: %s", e.Path, e.Reason)
+}
@@ -0,0 +1,161 @@
+package proxy
+
+import (
+	"Gox/config"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// FakeDNSPool FakeDNS地址池
+// Xray不对外暴露假IP映射表，这里记录通过本地DNS查询口获得的映射，供路由测试反查域名
+type FakeDNSPool struct {
+	mu        sync.RWMutex
+	ranges    []*net.IPNet
+	queryAddr string
+	domains   map[string]string // 假IP -> 域名
+}
+
+// NewFakeDNSPool 创建FakeDNS地址池
+func NewFakeDNSPool() *FakeDNSPool {
+	return &FakeDNSPool{
+		domains: make(map[string]string),
+	}
+}
+
+// Reset 按配置重置地址池，配置中不再包含的映射会被丢弃
+func (p *FakeDNSPool) Reset(cfg config.FakeDNSConfig) error {
+	var ranges []*net.IPNet
+	for _, pool := range fakeDNSPools(cfg) {
+		_, ipNet, err := net.ParseCIDR(pool.IPPool)
+		if err != nil {
+			return fmt.Errorf("invalid fakedns pool %s: %w", pool.IPPool, err)
+		}
+		ranges = append(ranges, ipNet)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.ranges = ranges
+	p.queryAddr = net.JoinHostPort("127.0.0.1", strconv.Itoa(fakeDNSQueryPort(cfg)))
+	for ip := range p.domains {
+		if !p.containsLocked(net.ParseIP(ip)) {
+			delete(p.domains, ip)
+		}
+	}
+	return nil
+}
+
+// Contains 检查IP是否属于假IP地址池
+func (p *FakeDNSPool) Contains(ip net.IP) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.containsLocked(ip)
+}
+
+// containsLocked 检查IP是否属于假IP地址池（不加锁）
+func (p *FakeDNSPool) containsLocked(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range p.ranges {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Remember 记录假IP与域名的映射
+func (p *FakeDNSPool) Remember(domain string, ip net.IP) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.containsLocked(ip) {
+		p.domains[ip.String()] = domain
+	}
+}
+
+// Lookup 根据假IP反查域名
+func (p *FakeDNSPool) Lookup(ip net.IP) (string, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	domain, ok := p.domains[ip.String()]
+	return domain, ok
+}
+
+// Resolve 通过运行中的Xray解析域名并记录分配到的假IP
+func (p *FakeDNSPool) Resolve(ctx context.Context, domain string) ([]net.IP, error) {
+	p.mu.RLock()
+	queryAddr := p.queryAddr
+	p.mu.RUnlock()
+
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			dialer := net.Dialer{Timeout: 2 * time.Second}
+			return dialer.DialContext(ctx, "udp", queryAddr)
+		},
+	}
+
+	addrs, err := resolver.LookupIPAddr(ctx, domain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s via fakedns: %w", domain, err)
+	}
+
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		p.Remember(domain, addr.IP)
+		ips = append(ips, addr.IP)
+	}
+	return ips, nil
+}
+
+// fakeDNSPools 生成FakeDNS地址池配置，未配置的字段使用默认值
+func fakeDNSPools(cfg config.FakeDNSConfig) []FakeDNSPoolConfig {
+	ipv4Pool := cfg.IPv4Pool
+	if ipv4Pool == "" {
+		ipv4Pool = config.DefaultFakeDNSIPv4Pool
+	}
+	ipv4Size := cfg.IPv4PoolSize
+	if ipv4Size <= 0 {
+		ipv4Size = config.DefaultFakeDNSPoolSize
+	}
+
+	pools := []FakeDNSPoolConfig{
+		{IPPool: ipv4Pool, PoolSize: ipv4Size},
+	}
+
+	if cfg.IPv6Pool != "" {
+		ipv6Size := cfg.IPv6PoolSize
+		if ipv6Size <= 0 {
+			ipv6Size = config.DefaultFakeDNSPoolSize
+		}
+		pools = append(pools, FakeDNSPoolConfig{IPPool: cfg.IPv6Pool, PoolSize: ipv6Size})
+	}
+
+	return pools
+}
+
+// fakeDNSQueryPort 获取本地DNS查询端口
+func fakeDNSQueryPort(cfg config.FakeDNSConfig) int {
+	if cfg.QueryPort <= 0 {
+		return config.DefaultFakeDNSQueryPort
+	}
+	return cfg.QueryPort
+}
+
+// generateDNSConfig 生成内置DNS配置，FakeDNS优先，其余查询交给上游DNS
+func generateDNSConfig(cfg config.FakeDNSConfig) *DNSConfig {
+	servers := []interface{}{"fakedns"}
+	for _, server := range cfg.DNSServers {
+		servers = append(servers, server)
+	}
+	if len(cfg.DNSServers) == 0 {
+		servers = append(servers, "localhost")
+	}
+	return &DNSConfig{Servers: servers}
+}
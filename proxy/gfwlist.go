@@ -0,0 +1,264 @@
+package proxy
+
+import (
+	"Gox/config"
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// GFWListProxyRuleID GFWList代理规则ID
+	GFWListProxyRuleID = "gfwlist-proxy"
+	// GFWListDirectRuleID GFWList例外(@@)规则ID
+	GFWListDirectRuleID = "gfwlist-direct"
+	// GFWListIPRuleID GFWList中IP条目规则ID
+	GFWListIPRuleID = "gfwlist-ip"
+)
+
+// GFWListImportResult GFWList导入结果
+type GFWListImportResult struct {
+	ProxyCount  int       `json:"proxyCount"`  // 代理条目数
+	DirectCount int       `json:"directCount"` // 例外(直连)条目数
+	IPCount     int       `json:"ipCount"`     // IP条目数
+	Unconverted []string  `json:"unconverted"` // 无法转换的规则
+	UpdatedAt   time.Time `json:"updatedAt"`   // 导入时间
+}
+
+// urlSchemePattern 匹配AutoProxy正则开头的协议部分
+var urlSchemePattern = regexp.MustCompile(`^\^?https\?:\\?/\\?/|^\^?https?:\\?/\\?/`)
+
+// FetchRuleSource 读取规则来源，支持本地路径和URL
+func FetchRuleSource(source string) ([]byte, error) {
+	if !isRemoteSource(source) {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rule source %s: %w", source, err)
+		}
+		return data, nil
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", source, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: unexpected status %s", source, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// ParseGFWList 将GFWList(base64包装的AutoProxy规则)转换为路由规则
+// @@例外规则生成直连规则并排在代理规则之前，无法转换的规则会在结果中列出
+func ParseGFWList(data []byte) ([]config.RoutingRuleConfig, *GFWListImportResult, error) {
+	text, err := decodeGFWList(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var proxyDomains, directDomains, proxyIPs []string
+	seen := make(map[string]bool)
+	result := &GFWListImportResult{UpdatedAt: time.Now()}
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "!") || strings.HasPrefix(line, "[") {
+			continue
+		}
+
+		exception := strings.HasPrefix(line, "@@")
+		entry, isIP, ok := convertAutoProxyRule(strings.TrimPrefix(line, "@@"))
+		if !ok {
+			result.Unconverted = append(result.Unconverted, line)
+			continue
+		}
+
+		key := fmt.Sprintf("%t|%t|%s", exception, isIP, entry)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		switch {
+		case isIP && exception:
+			// IP例外极少见，直连规则只支持域名，按无法转换处理
+			result.Unconverted = append(result.Unconverted, line)
+		case isIP:
+			proxyIPs = append(proxyIPs, entry)
+		case exception:
+			directDomains = append(directDomains, entry)
+		default:
+			proxyDomains = append(proxyDomains, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read gfwlist: %w", err)
+	}
+
+	result.ProxyCount = len(proxyDomains)
+	result.DirectCount = len(directDomains)
+	result.IPCount = len(proxyIPs)
+
+	var rules []config.RoutingRuleConfig
+	if len(directDomains) > 0 {
+		rules = append(rules, config.RoutingRuleConfig{
+			ID:          GFWListDirectRuleID,
+			Name:        "GFWList 例外",
+			Type:        config.RuleTypeDomain,
+			Values:      directDomains,
+			OutboundTag: "direct",
+			Enabled:     true,
+		})
+	}
+	if len(proxyDomains) > 0 {
+		rules = append(rules, config.RoutingRuleConfig{
+			ID:          GFWListProxyRuleID,
+			Name:        "GFWList",
+			Type:        config.RuleTypeDomain,
+			Values:      proxyDomains,
+			OutboundTag: "proxy",
+			Enabled:     true,
+		})
+	}
+	if len(proxyIPs) > 0 {
+		rules = append(rules, config.RoutingRuleConfig{
+			ID:          GFWListIPRuleID,
+			Name:        "GFWList IP",
+			Type:        config.RuleTypeIP,
+			Values:      proxyIPs,
+			OutboundTag: "proxy",
+			Enabled:     true,
+		})
+	}
+
+	return rules, result, nil
+}
+
+// ReplaceGFWListRules 用新导入的规则替换已有的GFWList规则
+// 已有规则保留原位置和启用状态，首次导入时追加到末尾
+func ReplaceGFWListRules(existing, imported []config.RoutingRuleConfig) []config.RoutingRuleConfig {
+	position := -1
+	enabled := make(map[string]bool)
+	merged := make([]config.RoutingRuleConfig, 0, len(existing)+len(imported))
+	for _, rule := range existing {
+		if isGFWListRule(rule.ID) {
+			if position < 0 {
+				position = len(merged)
+			}
+			enabled[rule.ID] = rule.Enabled
+			continue
+		}
+		merged = append(merged, rule)
+	}
+
+	for i := range imported {
+		if wasEnabled, ok := enabled[imported[i].ID]; ok {
+			imported[i].Enabled = wasEnabled
+		}
+	}
+
+	if position < 0 {
+		return append(merged, imported...)
+	}
+	result := append([]config.RoutingRuleConfig{}, merged[:position]...)
+	result = append(result, imported...)
+	return append(result, merged[position:]...)
+}
+
+// isGFWListRule 检查规则是否由GFWList导入
+func isGFWListRule(id string) bool {
+	return id == GFWListProxyRuleID || id == GFWListDirectRuleID || id == GFWListIPRuleID
+}
+
+// decodeGFWList 解码GFWList，未经base64包装的AutoProxy文本原样返回
+func decodeGFWList(data []byte) (string, error) {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("[AutoProxy")) || bytes.Contains(trimmed, []byte("||")) {
+		return string(trimmed), nil
+	}
+
+	compact := strings.Join(strings.Fields(string(trimmed)), "")
+	decoded, err := base64.StdEncoding.DecodeString(compact)
+	if err != nil {
+		if decoded, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(compact, "=")); err != nil {
+			return "", fmt.Errorf("gfwlist is neither AutoProxy text nor valid base64: %w", err)
+		}
+	}
+	return string(decoded), nil
+}
+
+// convertAutoProxyRule 转换单条AutoProxy规则，返回Xray条目以及是否为IP
+func convertAutoProxyRule(rule string) (string, bool, bool) {
+	switch {
+	case strings.HasPrefix(rule, "||"):
+		return hostEntry(strings.TrimPrefix(rule, "||"))
+	case strings.HasPrefix(rule, "|"):
+		u, err := url.Parse(strings.TrimSuffix(strings.TrimPrefix(rule, "|"), "|"))
+		if err != nil || u.Hostname() == "" {
+			return "", false, false
+		}
+		return hostEntry(u.Hostname())
+	case strings.HasPrefix(rule, "/") && strings.HasSuffix(rule, "/") && len(rule) > 2:
+		return convertAutoProxyRegex(rule[1 : len(rule)-1])
+	default:
+		return hostEntry(rule)
+	}
+}
+
+// hostEntry 从AutoProxy规则中取出主机部分并生成条目，包含通配符的规则无法转换
+func hostEntry(rule string) (string, bool, bool) {
+	host := rule
+	if idx := strings.IndexAny(host, "/^"); idx >= 0 {
+		host = host[:idx]
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.Trim(host, "."))
+
+	if host == "" || strings.ContainsAny(host, "*?|%=&") {
+		return "", false, false
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), true, true
+	}
+	if !strings.Contains(host, ".") {
+		return "", false, false
+	}
+	return "domain:" + host, false, true
+}
+
+// convertAutoProxyRegex 转换只作用于主机名的URL正则，依赖路径的正则无法转换
+func convertAutoProxyRegex(pattern string) (string, bool, bool) {
+	hostPattern := urlSchemePattern.ReplaceAllString(pattern, "^")
+	if hostPattern == pattern {
+		return "", false, false
+	}
+
+	// 主机名中不会出现斜杠，[^\/] 等价于任意字符
+	hostPattern = strings.ReplaceAll(hostPattern, `[^\/]`, `.`)
+	hostPattern = strings.ReplaceAll(hostPattern, `[^/]`, `.`)
+	hostPattern = strings.TrimSuffix(hostPattern, `(.*)`)
+	hostPattern = strings.TrimSuffix(hostPattern, `.*`)
+	if strings.Contains(hostPattern, "/") {
+		return "", false, false
+	}
+	if _, err := regexp.Compile(hostPattern); err != nil {
+		return "", false, false
+	}
+	return "regexp:" + hostPattern, false, true
+}
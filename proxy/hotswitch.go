@@ -0,0 +1,267 @@
+package proxy
+
+import (
+	"Gox/config"
+	"Gox/server"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// apiTag Xray API出站标签
+	apiTag = "api"
+	// apiInboundTag Xray API入站标签
+	apiInboundTag = "api-in"
+	// apiTimeout 调用Xray API的超时时间
+	apiTimeout = 5 * time.Second
+)
+
+// apiServices 启用的Xray API服务
+var apiServices = []string{"HandlerService", "StatsService"}
+
+// apiPort 获取Xray API端口
+func apiPort(inbound config.InboundConfig) int {
+	if inbound.APIPort <= 0 {
+		return config.DefaultAPIPort
+	}
+	return inbound.APIPort
+}
+
+// addAPIConfig 在配置中加入API服务、只监听回环地址的API入站以及对应的路由规则，并开启出站流量统计
+func addAPIConfig(xrayConfig *XrayConfig, port int) {
+	xrayConfig.API = &APIConfig{
+		Tag:      apiTag,
+		Services: apiServices,
+	}
+	xrayConfig.Stats = &StatsConfig{}
+	xrayConfig.Policy = &PolicyConfig{
+		System: SystemPolicy{
+			StatsOutboundUplink:   true,
+			StatsOutboundDownlink: true,
+		},
+	}
+	xrayConfig.Inbounds = append(xrayConfig.Inbounds, InboundConfig{
+		Tag:      apiInboundTag,
+		Listen:   "127.0.0.1",
+		Port:     port,
+		Protocol: "dokodemo-door",
+		Settings: map[string]interface{}{
+			"address": "127.0.0.1",
+		},
+	})
+	// API规则必须排在最前面，避免被其他规则截走
+	xrayConfig.Routing.Rules = append([]RuleConfig{{
+		Type:        "field",
+		OutboundTag: apiTag,
+		InboundTag:  []string{apiInboundTag},
+	}}, xrayConfig.Routing.Rules...)
+}
+
+// removeAPIConfig 去掉API服务、流量统计、API入站及其路由规则，用于不支持Xray API的内核
+func removeAPIConfig(xrayConfig *XrayConfig) {
+	xrayConfig.API = nil
+	xrayConfig.Stats = nil
+	xrayConfig.Policy = nil
+
+	inbounds := xrayConfig.Inbounds[:0]
+	for _, inbound := range xrayConfig.Inbounds {
+		if inbound.Tag != apiInboundTag {
+			inbounds = append(inbounds, inbound)
+		}
+	}
+	xrayConfig.Inbounds = inbounds
+
+	rules := xrayConfig.Routing.Rules[:0]
+	for _, rule := range xrayConfig.Routing.Rules {
+		if rule.OutboundTag != apiTag {
+			rules = append(rules, rule)
+		}
+	}
+	xrayConfig.Routing.Rules = rules
+}
+
+// apiAddress 配置中Xray API入站的地址，未启用API时返回空字符串
+func apiAddress(xrayConfig *XrayConfig) string {
+	if xrayConfig == nil {
+		return ""
+	}
+	for _, inbound := range xrayConfig.Inbounds {
+		if inbound.Tag == apiInboundTag {
+			return net.JoinHostPort(inbound.Listen, strconv.Itoa(inbound.Port))
+		}
+	}
+	return ""
+}
+
+// switchServer 代理运行中切换服务器时，只替换proxy出站而不重启Xray，本地入站保持监听
+// 入站、DNS或路由等其他配置有变化，或API调用失败时返回false，由调用方重启Xray
+// 生成配置和调用API期间不持有锁，期间代理被停止或重新启动时返回errStartInterrupted
+func (m *XrayProxyManager) switchServer(srv *server.ServerConfig) (bool, error) {
+	// 与启动互斥，避免切换期间另一次启动覆盖配置文件
+	m.launchMu.Lock()
+	defer m.launchMu.Unlock()
+
+	m.mu.RLock()
+	proc, running, activeCore, activeServer := m.proc, m.runningConfig, m.activeCore, m.activeServer
+	corePath, gen := m.corePath, m.restartGen
+	ready := proc != nil && m.state.status == StatusRunning && running != nil && activeCore != nil
+	m.mu.RUnlock()
+	if !ready {
+		return false, nil
+	}
+
+	// 完整配置由用户控制，切换到或离开完整配置的服务器都需要重启
+	if isCustomServer(srv) || isCustomServer(activeServer) {
+		return false, nil
+	}
+
+	// 只有支持API的内核才能热切换，切换到其他内核时需要重启
+	proxyCfg := m.proxyConfig()
+	core, err := m.selectCore(srv, proxyCfg)
+	if err != nil || core != activeCore || !core.Capabilities().hasFeature(FeatureHotSwitch) {
+		return false, nil
+	}
+
+	xrayConfig, err := m.buildCoreConfig(core, srv, proxyCfg)
+	if err != nil {
+		return false, nil
+	}
+	if !sameExceptProxyOutbound(running, xrayConfig) {
+		fmt.Println("Xray config changed beyond the proxy outbound, restarting")
+		return false, nil
+	}
+
+	// 使用合并自定义片段后的出站，保留结构体未定义的字段
+	outbound := proxyOutbound(xrayConfig)
+	if outbound == nil {
+		return false, nil
+	}
+
+	// 替换出站前先把已产生的流量计入旧服务器，切换完成前暂停采样
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+	if err := m.sampleTrafficLocked(proc); err != nil {
+		fmt.Printf("Failed to query traffic stats before switching: %v\n", err)
+	}
+
+	if err := m.replaceOutbound(corePath, apiAddress(running), outbound); err != nil {
+		fmt.Printf("Failed to hot switch server, restarting: %v\n", err)
+		return false, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// 切换期间用户停止或重新启动了代理
+	if m.restartGen != gen {
+		return false, errStartInterrupted
+	}
+	// 切换期间进程崩溃，由调用方按新服务器重新启动
+	if m.proc != proc || m.state.status != StatusRunning {
+		return false, nil
+	}
+
+	// 配置文件保持与运行中的Xray一致，崩溃重启时使用新服务器
+	if err := m.saveCoreConfig(core, xrayConfig, srv); err != nil {
+		fmt.Printf("Failed to save xray config: %v\n", err)
+	}
+	m.runningConfig = xrayConfig
+	m.activeServer = srv
+	m.state.announce(srv.ID)
+	return true, nil
+}
+
+// replaceOutbound 通过Xray API删除旧的proxy出站并添加新的出站
+func (m *XrayProxyManager) replaceOutbound(binary, addr string, outbound map[string]interface{}) error {
+	if addr == "" {
+		return fmt.Errorf("xray api is not enabled")
+	}
+
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"outbounds": []interface{}{outbound},
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbound: %w", err)
+	}
+	outboundPath := filepath.Join(m.workDir, "xray_outbound.json")
+	if err := os.WriteFile(outboundPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write outbound config: %w", err)
+	}
+	defer os.Remove(outboundPath)
+
+	if _, err := xrayAPI(binary, "rmo", "--server="+addr, "proxy"); err != nil {
+		return err
+	}
+	_, err = xrayAPI(binary, "ado", "--server="+addr, outboundPath)
+	return err
+}
+
+// xrayAPI 调用xray api子命令并返回标准输出，Windows下不弹出控制台窗口
+func xrayAPI(binary string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, binary, append([]string{"api"}, args...)...)
+	hideWindow(cmd)
+	output, err := cmd.Output()
+	if err != nil {
+		detail := string(output)
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			detail += string(exitErr.Stderr)
+		}
+		return nil, fmt.Errorf("xray api %s failed: %w: %s", args[0], err, strings.TrimSpace(detail))
+	}
+	return output, nil
+}
+
+// sameExceptProxyOutbound 比较两份配置除proxy出站以外是否一致
+func sameExceptProxyOutbound(a, b *XrayConfig) bool {
+	keyA, errA := configWithoutProxy(a)
+	keyB, errB := configWithoutProxy(b)
+	return errA == nil && errB == nil && keyA == keyB
+}
+
+// configWithoutProxy 序列化去掉proxy出站后的配置，合并过自定义片段时使用合并结果
+func configWithoutProxy(xrayConfig *XrayConfig) (string, error) {
+	document, err := configDocument(xrayConfig)
+	if err != nil {
+		return "", err
+	}
+	var outbounds []interface{}
+	if list, ok := document["outbounds"].([]interface{}); ok {
+		for _, outbound := range list {
+			if object, ok := outbound.(map[string]interface{}); ok && object["tag"] == "proxy" {
+				continue
+			}
+			outbounds = append(outbounds, outbound)
+		}
+	}
+	document["outbounds"] = outbounds
+	data, err := json.Marshal(document)
+	return string(data), err
+}
+
+// proxyOutbound 获取配置中的proxy出站
+func proxyOutbound(xrayConfig *XrayConfig) map[string]interface{} {
+	document, err := configDocument(xrayConfig)
+	if err != nil {
+		return nil
+	}
+	list, _ := document["outbounds"].([]interface{})
+	for _, outbound := range list {
+		if object, ok := outbound.(map[string]interface{}); ok && object["tag"] == "proxy" {
+			return object
+		}
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package proxy
+
+import (
+	"Gox/config"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// PortInUseError 入站端口被其他进程占用
+type PortInUseError struct {
+	Inbound string // 入站标签
+	Address string // 监听地址
+	Port    int    // 端口
+	PID     int    // 占用进程ID，未知时为0
+	Process string // 占用进程名，未知时为空
+}
+
+// Error 实现error接口
+func (e *PortInUseError) Error() string {
+	if e.PID > 0 {
+		return fmt.Sprintf("port %d (%s) is already in use by %s (pid %d)", e.Port, e.Inbound, e.Process, e.PID)
+	}
+	return fmt.Sprintf("port %d (%s) is already in use", e.Port, e.Inbound)
+}
+
+// generateInbounds 生成本地入站配置，局域网模式下改为监听局域网地址并按需启用认证
+func (m *XrayProxyManager) generateInbounds(proxyCfg config.ProxyConfig) ([]InboundConfig, error) {
+	inboundCfg := proxyCfg.Inbound
+	listen := inboundCfg.Listen
+	if listen == "" {
+		listen = config.DefaultListenAddress
+	}
+
+	var creds *LANCredentials
+	if proxyCfg.LAN.Enabled {
+		lanListen, err := lanListenAddress(proxyCfg.LAN)
+		if err != nil {
+			return nil, err
+		}
+		listen = lanListen
+
+		if proxyCfg.LAN.AuthEnabled {
+			if creds, err = m.lanCredentials(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	socksSettings := map[string]interface{}{
+		"auth": "noauth",
+		"udp":  inboundCfg.UDP,
+	}
+	var httpSettings map[string]interface{}
+	if creds != nil {
+		socksSettings["auth"] = "password"
+		socksSettings["accounts"] = inboundAccounts(creds)
+		httpSettings = map[string]interface{}{
+			"accounts": inboundAccounts(creds),
+		}
+	}
+
+	var sniffing *SniffingConfig
+	if inboundCfg.Sniffing || proxyCfg.FakeDNS.Enabled {
+		destOverride := append([]string{}, inboundCfg.DestOverride...)
+		if len(destOverride) == 0 {
+			destOverride = []string{"http", "tls"}
+		}
+		if proxyCfg.FakeDNS.Enabled && !containsString(destOverride, "fakedns") {
+			destOverride = append(destOverride, "fakedns")
+		}
+		sniffing = &SniffingConfig{
+			Enabled:      true,
+			DestOverride: destOverride,
+			RouteOnly:    inboundCfg.RouteOnly,
+		}
+	}
+
+	var inbounds []InboundConfig
+	if inboundCfg.SocksPort > 0 {
+		inbounds = append(inbounds, InboundConfig{
+			Tag:      "socks-in",
+			Listen:   listen,
+			Port:     inboundCfg.SocksPort,
+			Protocol: "socks",
+			Settings: socksSettings,
+			Sniffing: sniffing,
+		})
+	}
+	if inboundCfg.HTTPPort > 0 {
+		inbounds = append(inbounds, InboundConfig{
+			Tag:      "http-in",
+			Listen:   listen,
+			Port:     inboundCfg.HTTPPort,
+			Protocol: "http",
+			Settings: httpSettings,
+			Sniffing: sniffing,
+		})
+	}
+	if inboundCfg.MixedPort > 0 {
+		// Xray的SOCKS入站同时接受HTTP代理请求
+		inbounds = append(inbounds, InboundConfig{
+			Tag:      "mixed-in",
+			Listen:   listen,
+			Port:     inboundCfg.MixedPort,
+			Protocol: "socks",
+			Settings: socksSettings,
+			Sniffing: sniffing,
+		})
+	}
+
+	if proxyCfg.FakeDNS.Enabled {
+		// 本地DNS查询口，路由测试通过它获取假IP映射
+		inbounds = append(inbounds, InboundConfig{
+			Tag:      "dns-in",
+			Listen:   "127.0.0.1",
+			Port:     fakeDNSQueryPort(proxyCfg.FakeDNS),
+			Protocol: "dokodemo-door",
+			Settings: map[string]interface{}{
+				"address": "1.1.1.1",
+				"port":    53,
+				"network": "tcp,udp",
+			},
+		})
+	}
+
+	return inbounds, nil
+}
+
+// validateInbounds 校验入站配置：至少启用一个代理端口，端口不能重复
+func validateInbounds(inbounds []InboundConfig) error {
+	proxyInbounds := 0
+	seen := make(map[int]string)
+	for _, inbound := range inbounds {
+		if inbound.Port <= 0 || inbound.Port > 65535 {
+			return fmt.Errorf("invalid port %d for inbound %s", inbound.Port, inbound.Tag)
+		}
+		if other, ok := seen[inbound.Port]; ok {
+			return fmt.Errorf("inbounds %s and %s use the same port %d", other, inbound.Tag, inbound.Port)
+		}
+		seen[inbound.Port] = inbound.Tag
+		if inbound.Tag != "dns-in" && inbound.Tag != apiInboundTag {
+			proxyInbounds++
+		}
+	}
+	if proxyInbounds == 0 {
+		return fmt.Errorf("no local inbound is enabled")
+	}
+	return nil
+}
+
+// checkInboundPorts 启动前检查入站端口是否空闲
+// 刚停止的Xray可能尚未释放端口，因此在超时前会重试
+func checkInboundPorts(inbounds []InboundConfig, timeout time.Duration) error {
+	for _, inbound := range inbounds {
+		deadline := time.Now().Add(timeout)
+		for {
+			err := probePort(inbound)
+			if err == nil {
+				break
+			}
+			var inUse *PortInUseError
+			if !errors.As(err, &inUse) {
+				return err
+			}
+			if time.Now().After(deadline) {
+				inUse.PID, inUse.Process = findPortOwner(inUse.Port)
+				return inUse
+			}
+			time.Sleep(200 * time.Millisecond)
+		}
+	}
+	return nil
+}
+
+// probePort 尝试监听入站端口
+func probePort(inbound InboundConfig) error {
+	addr := net.JoinHostPort(inbound.Listen, strconv.Itoa(inbound.Port))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		if !isAddrInUse(err) {
+			return fmt.Errorf("cannot listen on %s for inbound %s: %w", addr, inbound.Tag, err)
+		}
+		return &PortInUseError{
+			Inbound: inbound.Tag,
+			Address: inbound.Listen,
+			Port:    inbound.Port,
+		}
+	}
+	return listener.Close()
+}
+
+// containsString 检查切片中是否包含指定字符串
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
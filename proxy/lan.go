@@ -0,0 +1,219 @@
+package proxy
+
+import (
+	"Gox/config"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// LANCredentials 局域网入站认证信息
+type LANCredentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// LANInfo 局域网共享信息
+type LANInfo struct {
+	Enabled     bool            `json:"enabled"`     // 是否允许局域网连接
+	Listen      string          `json:"listen"`      // 实际监听地址
+	Addresses   []string        `json:"addresses"`   // 局域网可访问的地址
+	URLs        []string        `json:"urls"`        // 可直接使用的代理地址
+	Credentials *LANCredentials `json:"credentials"` // 认证信息，未启用认证时为空
+}
+
+// lanCredentialsMu 保护认证文件的并发读写
+var lanCredentialsMu sync.Mutex
+
+// lanCredentialsPath 认证信息文件路径
+func (m *XrayProxyManager) lanCredentialsPath() string {
+	return filepath.Join(m.workDir, "lan_credentials.json")
+}
+
+// lanCredentials 读取认证信息，不存在时生成随机用户名和密码
+func (m *XrayProxyManager) lanCredentials() (*LANCredentials, error) {
+	lanCredentialsMu.Lock()
+	defer lanCredentialsMu.Unlock()
+
+	data, err := os.ReadFile(m.lanCredentialsPath())
+	if err == nil {
+		var creds LANCredentials
+		if err := json.Unmarshal(data, &creds); err == nil && creds.Username != "" && creds.Password != "" {
+			return &creds, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read lan credentials: %w", err)
+	}
+
+	return m.generateLANCredentialsLocked()
+}
+
+// RegenerateLANCredentials 重新生成局域网认证信息，需重启代理后生效
+func (m *XrayProxyManager) RegenerateLANCredentials() (*LANCredentials, error) {
+	lanCredentialsMu.Lock()
+	defer lanCredentialsMu.Unlock()
+	return m.generateLANCredentialsLocked()
+}
+
+// generateLANCredentialsLocked 生成并保存随机认证信息（调用方需持有锁）
+func (m *XrayProxyManager) generateLANCredentialsLocked() (*LANCredentials, error) {
+	username, err := randomToken(4)
+	if err != nil {
+		return nil, err
+	}
+	password, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	creds := &LANCredentials{
+		Username: "gox-" + username,
+		Password: password,
+	}
+
+	data, err := json.MarshalIndent(creds, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal lan credentials: %w", err)
+	}
+	// 仅当前用户可读写，不放进会下发给前端的config.json
+	if err := os.WriteFile(m.lanCredentialsPath(), data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write lan credentials: %w", err)
+	}
+	return creds, nil
+}
+
+// randomToken 生成指定字节数的随机十六进制串
+func randomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// lanListenAddress 局域网模式下的监听地址，指定网卡时使用该网卡的IPv4地址
+func lanListenAddress(lan config.LANConfig) (string, error) {
+	if lan.Interface == "" {
+		return "0.0.0.0", nil
+	}
+
+	addrs, err := interfaceIPv4Addrs(lan.Interface)
+	if err != nil {
+		return "", err
+	}
+	if len(addrs) == 0 {
+		return "", fmt.Errorf("interface %s has no ipv4 address", lan.Interface)
+	}
+	return addrs[0], nil
+}
+
+// interfaceIPv4Addrs 获取网卡的IPv4地址
+func interfaceIPv4Addrs(name string) ([]string, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("interface %s not found: %w", name, err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get addresses of %s: %w", name, err)
+	}
+
+	var result []string
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+			result = append(result, ipNet.IP.String())
+		}
+	}
+	return result, nil
+}
+
+// lanAddresses 局域网内可访问本机的IPv4地址
+func lanAddresses(listen string) []string {
+	if listen != "0.0.0.0" {
+		return []string{listen}
+	}
+
+	var result []string
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := interfaceIPv4Addrs(iface.Name)
+		if err != nil {
+			continue
+		}
+		result = append(result, addrs...)
+	}
+	return result
+}
+
+// inboundAccounts 生成入站认证账号配置
+func inboundAccounts(creds *LANCredentials) []map[string]interface{} {
+	return []map[string]interface{}{
+		{
+			"user": creds.Username,
+			"pass": creds.Password,
+		},
+	}
+}
+
+// GetLANInfo 获取局域网共享信息
+func (m *XrayProxyManager) GetLANInfo() (*LANInfo, error) {
+	proxyCfg := m.proxyConfig()
+	info := &LANInfo{Enabled: proxyCfg.LAN.Enabled}
+	if !proxyCfg.LAN.Enabled {
+		return info, nil
+	}
+
+	listen, err := lanListenAddress(proxyCfg.LAN)
+	if err != nil {
+		return nil, err
+	}
+	info.Listen = listen
+	info.Addresses = lanAddresses(listen)
+
+	var userinfo *url.Userinfo
+	if proxyCfg.LAN.AuthEnabled {
+		creds, err := m.lanCredentials()
+		if err != nil {
+			return nil, err
+		}
+		info.Credentials = creds
+		userinfo = url.UserPassword(creds.Username, creds.Password)
+	}
+
+	inbounds, err := m.generateInbounds(proxyCfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range info.Addresses {
+		for _, inbound := range inbounds {
+			scheme := ""
+			switch inbound.Tag {
+			case "socks-in", "mixed-in":
+				scheme = "socks5"
+			case "http-in":
+				scheme = "http"
+			default:
+				continue
+			}
+			u := url.URL{
+				Scheme: scheme,
+				User:   userinfo,
+				Host:   net.JoinHostPort(addr, strconv.Itoa(inbound.Port)),
+			}
+			info.URLs = append(info.URLs, u.String())
+		}
+	}
+	return info, nil
+}
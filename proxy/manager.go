@@ -1,129 +1,278 @@
 package proxy
 
 import (
+	"Gox/config"
+	"Gox/logger"
 	"Gox/server"
 	"context"
 	"embed"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"sync"
 	"time"
 )
 
+// errStartInterrupted 启动过程中代理已被停止或重新启动
+var errStartInterrupted = errors.New("core start was interrupted")
+
 // XrayProxyManager Xray代理管理器
 type XrayProxyManager struct {
-	mu           sync.RWMutex
-	status       ProxyStatus
-	activeServer *server.ServerConfig
-	cmd          *exec.Cmd
-	cancel       context.CancelFunc
-	xrayPath     string
-	configPath   string
+	mu            sync.RWMutex
+	launchMu      sync.Mutex // 保证同一时间只有一次启动在生成配置
+	state         *statusMachine
+	activeServer  *server.ServerConfig
+	proc          *coreProcess
+	runningConfig *XrayConfig
+	cores         map[string]Core
+	coreOrder     []string
+	activeCore    Core
+	corePath      string
+	cancel        context.CancelFunc
+	workDir       string
+	xrayPath      string
+	configPath    string
+	fakeDNS       *FakeDNSPool
+	pac           *PACServer
+	accessLog     *AccessLogBuffer
+	accessLogFile io.WriteCloser
+	accessLogDone chan struct{} // 访问日志读取结束并关闭文件后关闭
+	versionMu     sync.Mutex
+	version       string
+	coreSource    string
+	coreBinaries  embed.FS
+	// xrayConfigured 确定当前Xray时设置中的二进制路径，路径变化后重新查找
+	xrayConfigured string
+	// runningVersion 运行中内核的版本
+	runningVersion string
+	// 流量统计
+	traffic         TrafficStats
+	trafficListener func(TrafficStats)
+	// statsMu 保证同一时间只有一次流量采样，热切换期间暂停采样
+	statsMu   sync.Mutex
+	meter     trafficMeter
+	meterProc *coreProcess
+	// 带宽测速，同一时间只运行一个
+	speedTestSlot      chan struct{}
+	speedTestMu        sync.Mutex
+	speedTestCtx       context.Context
+	speedTestCancel    context.CancelFunc
+	speedTestTransport func(proxyAddr string) http.RoundTripper
+
+	// 自动重启状态
+	restartTimer  *time.Timer
+	restartGen    int
+	recentCrashes []time.Time
+	crashes       []CrashRecord
 }
 
 // NewXrayProxyManager 创建新的Xray代理管理器
-func NewXrayProxyManager(workDir string, xrayBinary embed.FS) (*XrayProxyManager, error) {
-	xrayPath := filepath.Join(workDir, "xray.exe")
-	configPath := filepath.Join(workDir, "xray_config.json")
+func NewXrayProxyManager(workDir string, coreBinaries embed.FS) (*XrayProxyManager, error) {
+	manager := &XrayProxyManager{
+		state:        newStatusMachine(),
+		workDir:      workDir,
+		xrayPath:     filepath.Join(workDir, xraySpec.executableName()),
+		configPath:   filepath.Join(workDir, coreConfigFile(config.CoreXray)),
+		fakeDNS:      NewFakeDNSPool(),
+		pac:          NewPACServer(),
+		accessLog:    NewAccessLogBuffer(config.DefaultAccessLogRecords),
+		coreBinaries: coreBinaries,
+
+		accessLogFile:      logger.NewAccessLogWriter(),
+		speedTestSlot:      make(chan struct{}, 1),
+		speedTestTransport: socksTransport,
+	}
 
-	// 提取嵌入的xray.exe到工作目录
-	if err := extractXrayBinary(xrayPath, xrayBinary); err != nil {
-		return nil, fmt.Errorf("failed to extract xray binary: %w", err)
+	manager.cores = map[string]Core{
+		config.CoreXray:    &xrayCore{manager: manager},
+		config.CoreSingBox: newSingBoxCore(workDir, coreBinaries),
 	}
+	manager.coreOrder = []string{config.CoreXray, config.CoreSingBox}
 
-	return &XrayProxyManager{
-		status:     StatusStopped,
-		xrayPath:   xrayPath,
-		configPath: configPath,
-	}, nil
+	// 结束上次异常退出时遗留的内核进程，否则Windows下无法覆盖正在运行的二进制
+	if err := manager.cleanupOrphan(); err != nil {
+		fmt.Printf("Failed to clean up orphan core process: %v\n", err)
+	}
+
+	core, err := manager.resolveActiveXray()
+	if err != nil {
+		return nil, err
+	}
+	manager.useXray(core, manager.proxyConfig().XrayBinaryPath)
+	fmt.Printf("Using xray %s from %s (%s)\n", core.Version, core.Path, core.Source)
+
+	return manager, nil
 }
 
-// extractXrayBinary 提取嵌入的xray.exe到指定路径
-func extractXrayBinary(targetPath string, xrayBinary embed.FS) error {
-	// 检查文件是否已存在且是最新的
-	if _, err := os.Stat(targetPath); err == nil {
-		// 文件已存在，可以选择跳过或覆盖
-		// 这里选择跳过以提高性能
-		return nil
+// StartProxy 启动代理
+// 等待内核就绪期间不持有锁，状态保持为connecting，观察者可以看到连接中的状态
+func (m *XrayProxyManager) StartProxy(ctx context.Context, config *server.ServerConfig) error {
+	return m.startProxy(ctx, config, -1)
+}
+
+// startProxy 启动代理，restartGen为-1表示用户主动启动，否则为自动重启时的重启代次
+func (m *XrayProxyManager) startProxy(ctx context.Context, config *server.ServerConfig, restartGen int) error {
+	// 运行中切换服务器时优先热切换出站
+	if restartGen < 0 {
+		if switched, err := m.switchServer(config); switched || err != nil {
+			return err
+		}
 	}
 
-	// 从embed.FS读取xray.exe
-	data, err := xrayBinary.ReadFile("resources/xray.exe")
+	proc, inbounds, err := m.launch(ctx, config, restartGen)
 	if err != nil {
-		return fmt.Errorf("failed to read embedded xray binary: %w", err)
+		return err
+	}
+
+	readyErr := waitForReady(proc, inbounds, startupTimeout)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// 等待期间代理已被停止或重新启动
+	if m.proc != proc {
+		if readyErr != nil {
+			return readyErr
+		}
+		return errStartInterrupted
 	}
 
-	// 确保目标目录存在
-	dir := filepath.Dir(targetPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+	// 就绪后立即退出时监控goroutine可能已经跳过了处理
+	if readyErr == nil {
+		select {
+		case <-proc.done:
+			readyErr = proc.startupError(fmt.Sprintf("process exited with code %d", proc.exitCode()))
+		default:
+		}
 	}
 
-	// 写入文件
-	if err := os.WriteFile(targetPath, data, 0755); err != nil {
-		return fmt.Errorf("failed to write xray binary: %w", err)
+	if readyErr != nil {
+		m.teardownLocked()
+		return m.failLocked(readyErr)
 	}
 
+	m.setStatusLocked(StatusRunning, nil)
+
+	// PAC服务失败不影响代理本身
+	if err := m.refreshPAC(m.proxyConfig(), true); err != nil {
+		fmt.Printf("Failed to start pac server: %v\n", err)
+	}
 	return nil
 }
 
-// StartProxy 启动代理
-func (m *XrayProxyManager) StartProxy(ctx context.Context, config *server.ServerConfig) error {
+// launch 选择内核、生成配置并启动内核进程，返回进程和需要等待就绪的入站
+// 检查端口和配置需要等待，在锁外进行；同一时间只有一次启动在生成配置，避免互相覆盖配置文件
+func (m *XrayProxyManager) launch(ctx context.Context, config *server.ServerConfig, restartGen int) (*coreProcess, []InboundConfig, error) {
+	m.launchMu.Lock()
+	defer m.launchMu.Unlock()
+
+	proxyCfg := m.proxyConfig()
+	core, binary, gen, err := m.beginLaunch(config, proxyCfg, restartGen)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 完整配置的服务器原样运行，不生成配置
+	var xrayConfig *XrayConfig
+	if isCustomServer(config) {
+		xrayConfig, err = m.prepareCustomConfig(core, binary.Path, config)
+	} else {
+		xrayConfig, err = m.prepareGeneratedConfig(core, config, proxyCfg)
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// 如果已经在运行，先停止
-	if m.status == StatusRunning {
-		if err := m.stopProxyInternal(); err != nil {
-			return fmt.Errorf("failed to stop existing proxy: %w", err)
+	// 准备配置期间用户已经停止或重新启动了代理
+	if gen != m.restartGen || m.proc != nil {
+		if restartGen >= 0 {
+			return nil, nil, errRestartCancelled
 		}
+		return nil, nil, errStartInterrupted
 	}
-
-	m.status = StatusConnecting
-	m.activeServer = config
-
-	// 生成Xray配置文件
-	xrayConfig := m.generateXrayConfig(config)
-	if err := m.saveXrayConfig(xrayConfig); err != nil {
-		return fmt.Errorf("failed to save xray config: %w", err)
+	if err != nil {
+		return nil, nil, m.failLocked(err)
 	}
 
 	// 创建上下文和取消函数
 	ctx, cancel := context.WithCancel(ctx)
+
+	// 启动内核进程
+	proc, err := startCoreProcess(core.Command(ctx, binary.Path, m.configPath), core)
+	if err != nil {
+		cancel()
+		return nil, nil, m.failLocked(err)
+	}
+	m.activeCore = core
+	m.corePath = binary.Path
+	m.runningVersion = binary.Version
+	m.proc = proc
+	m.runningConfig = xrayConfig
 	m.cancel = cancel
+	m.writePIDFile(proc)
+	m.followAccessLog(proc, xrayConfig)
 
-	// 启动Xray进程
-	m.cmd = exec.CommandContext(ctx, m.xrayPath, "-config", m.configPath)
-	m.cmd.Stdout = os.Stdout
-	m.cmd.Stderr = os.Stderr
+	// 启动监控goroutine
+	go m.monitorProcess(proc)
+	go m.pollTraffic(proc)
+
+	return proc, xrayConfig.Inbounds, nil
+}
 
-	if err := m.cmd.Start(); err != nil {
-		m.status = StatusError
-		return fmt.Errorf("failed to start xray process: %w", err)
+// beginLaunch 停止正在运行的内核并选择新内核，返回本次启动对应的重启代次
+func (m *XrayProxyManager) beginLaunch(config *server.ServerConfig, proxyCfg config.ProxyConfig, restartGen int) (Core, *CoreInfo, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if restartGen < 0 {
+		m.cancelRestartLocked()
+	} else if restartGen != m.restartGen {
+		return nil, nil, 0, errRestartCancelled
+	} else {
+		m.restartTimer = nil
 	}
 
-	// 启动监控goroutine
-	go m.monitorProcess()
+	// 如果已经在运行或正在启动，先停止
+	if m.proc != nil {
+		if err := m.stopProxyInternal(); err != nil {
+			return nil, nil, 0, fmt.Errorf("failed to stop existing proxy: %w", err)
+		}
+	}
+
+	m.activeServer = config
+	m.setStatusLocked(StatusConnecting, nil)
 
-	// 等待一段时间确认启动成功
-	time.Sleep(2 * time.Second)
-	if m.cmd.Process == nil || m.cmd.ProcessState != nil {
-		m.status = StatusError
-		return fmt.Errorf("xray process failed to start or exited immediately")
+	core, err := m.selectCore(config, proxyCfg)
+	if err != nil {
+		return nil, nil, 0, m.failLocked(err)
+	}
+	binary, err := core.Binary()
+	if err != nil {
+		return nil, nil, 0, m.failLocked(err)
 	}
 
-	m.status = StatusRunning
-	return nil
+	m.configPath = filepath.Join(m.workDir, coreConfigFile(core.Name()))
+	return core, binary, m.restartGen, nil
 }
 
-// StopProxy 停止代理
+// StopProxy 停止代理，并等待访问日志读完剩余记录后关闭文件
 func (m *XrayProxyManager) StopProxy() error {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-	return m.stopProxyInternal()
+	m.cancelRestartLocked()
+	err := m.stopProxyInternal()
+	followed := m.accessLogDone
+	m.mu.Unlock()
+
+	if followed != nil {
+		select {
+		case <-followed:
+		case <-time.After(stopTimeout):
+		}
+	}
+	return err
 }
 
 // Start 启动代理（简化版本）
@@ -138,28 +287,45 @@ func (m *XrayProxyManager) Stop() error {
 
 // stopProxyInternal 内部停止代理方法（不加锁）
 func (m *XrayProxyManager) stopProxyInternal() error {
+	err := m.teardownLocked()
+	m.setStatusLocked(StatusStopped, nil)
+	m.activeServer = nil
+	return err
+}
+
+// teardownLocked 停止内核进程及附属服务，不改变状态（调用方需持有锁）
+func (m *XrayProxyManager) teardownLocked() error {
+	var stopErr error
+	if m.proc != nil {
+		proc := m.proc
+		// 先解除关联，监控goroutine据此判断是主动停止
+		m.proc = nil
+		m.runningConfig = nil
+		stopErr = proc.stop(stopTimeout)
+		m.removePIDFile()
+	}
+
+	// 进程退出后再取消上下文，避免CommandContext直接强制结束进程
 	if m.cancel != nil {
 		m.cancel()
 		m.cancel = nil
 	}
 
-	if m.cmd != nil && m.cmd.Process != nil {
-		if err := m.cmd.Process.Kill(); err != nil {
-			return fmt.Errorf("failed to kill xray process: %w", err)
-		}
-		m.cmd = nil
-	}
+	m.pac.Stop()
+	return stopErr
+}
 
-	m.status = StatusStopped
-	m.activeServer = nil
-	return nil
+// failLocked 切换到错误状态并返回原错误（调用方需持有锁）
+func (m *XrayProxyManager) failLocked(err error) error {
+	m.setStatusLocked(StatusError, err)
+	return err
 }
 
 // GetStatus 获取代理状态
 func (m *XrayProxyManager) GetStatus() ProxyStatus {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	return m.status
+	return m.state.status
 }
 
 // GetActiveServer 获取当前活动的服务器配置
@@ -174,57 +340,57 @@ func (m *XrayProxyManager) IsRunning() bool {
 	return m.GetStatus() == StatusRunning
 }
 
-// monitorProcess 监控Xray进程
-func (m *XrayProxyManager) monitorProcess() {
-	if m.cmd == nil {
-		return
-	}
+// monitorProcess 监控内核进程，运行中的进程意外退出时交给重启策略处理
+func (m *XrayProxyManager) monitorProcess(proc *coreProcess) {
+	<-proc.done
 
-	err := m.cmd.Wait()
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if err != nil {
-		fmt.Printf("Xray process exited with error: %v\n", err)
-		m.status = StatusError
-	} else {
-		m.status = StatusStopped
+	// 主动停止或已被新进程替换；启动阶段的退出由StartProxy处理
+	if m.proc != proc || m.state.status != StatusRunning {
+		return
 	}
 
-	m.cmd = nil
-	m.activeServer = nil
+	srv := m.activeServer
+	record := CrashRecord{
+		Time:     time.Now(),
+		ExitCode: proc.exitCode(),
+		Reason:   "process exited unexpectedly",
+		Stderr:   proc.stderr.String(),
+	}
+	if proc.err != nil {
+		record.Reason = proc.err.Error()
+	}
+	if srv != nil {
+		record.Server = srv.Name
+	}
+
+	m.teardownLocked()
+	m.handleCrashLocked(srv, record)
+}
+
+// proxyConfig 获取当前代理配置，配置未加载时使用默认值
+func (m *XrayProxyManager) proxyConfig() config.ProxyConfig {
+	if cfg := config.GetConfig(); cfg != nil {
+		return cfg.Proxy
+	}
+	return config.GetDefaultConfig().Proxy
 }
 
-// generateXrayConfig 生成Xray配置
-func (m *XrayProxyManager) generateXrayConfig(config *server.ServerConfig) *XrayConfig {
+// generateXrayConfig 按代理配置生成Xray配置
+func (m *XrayProxyManager) generateXrayConfig(config *server.ServerConfig, proxyCfg config.ProxyConfig) (*XrayConfig, error) {
+	inbounds, err := m.generateInbounds(proxyCfg)
+	if err != nil {
+		return nil, err
+	}
+
 	xrayConfig := &XrayConfig{
 		Log: LogConfig{
 			LogLevel: "warning",
+			Access:   m.accessLogPath(proxyCfg.AccessLog),
 		},
-		Inbounds: []InboundConfig{
-			{
-				Tag:      "socks-in",
-				Port:     1080,
-				Protocol: "socks",
-				Settings: map[string]interface{}{
-					"auth": "noauth",
-					"udp":  true,
-				},
-				Sniffing: &SniffingConfig{
-					Enabled:      true,
-					DestOverride: []string{"http", "tls"},
-				},
-			},
-			{
-				Tag:      "http-in",
-				Port:     1081,
-				Protocol: "http",
-				Sniffing: &SniffingConfig{
-					Enabled:      true,
-					DestOverride: []string{"http", "tls"},
-				},
-			},
-		},
+		Inbounds: inbounds,
 		Outbounds: []OutboundConfig{
 			m.generateOutboundConfig(config),
 			{
@@ -236,24 +402,21 @@ func (m *XrayProxyManager) generateXrayConfig(config *server.ServerConfig) *Xray
 				Protocol: "blackhole",
 			},
 		},
-		Routing: RoutingConfig{
-			DomainStrategy: "IPIfNonMatch",
-			Rules: []RuleConfig{
-				{
-					Type:        "field",
-					OutboundTag: "direct",
-					Domain:      []string{"geosite:cn"},
-				},
-				{
-					Type:        "field",
-					OutboundTag: "direct",
-					IP:          []string{"geoip:cn", "geoip:private"},
-				},
-			},
-		},
+		Routing: m.generateRoutingConfig(proxyCfg),
+	}
+
+	if proxyCfg.FakeDNS.Enabled {
+		xrayConfig.DNS = generateDNSConfig(proxyCfg.FakeDNS)
+		xrayConfig.FakeDNS = fakeDNSPools(proxyCfg.FakeDNS)
+		xrayConfig.Outbounds = append(xrayConfig.Outbounds, OutboundConfig{
+			Tag:      "dns-out",
+			Protocol: "dns",
+		})
 	}
 
-	return xrayConfig
+	addAPIConfig(xrayConfig, apiPort(proxyCfg.Inbound))
+
+	return xrayConfig, nil
 }
 
 // generateOutboundConfig 生成出站配置
@@ -329,31 +492,116 @@ func (m *XrayProxyManager) generateOutboundConfig(config *server.ServerConfig) O
 			outbound.StreamSettings.TLSSettings = map[string]interface{}{
 				"serverName": config.SNI,
 			}
+			if len(config.ALPN) > 0 {
+				outbound.StreamSettings.TLSSettings["alpn"] = config.ALPN
+			}
+			if config.Insecure {
+				outbound.StreamSettings.TLSSettings["allowInsecure"] = true
+			}
 		}
 
-		if config.Network == "ws" {
+		switch config.Network {
+		case "ws":
 			outbound.StreamSettings.WSSettings = map[string]interface{}{
 				"path": config.Path,
 				"headers": map[string]interface{}{
 					"Host": config.Host,
 				},
 			}
+		case "grpc":
+			// gRPC的服务名沿用路径字段
+			outbound.StreamSettings.GRPCSettings = map[string]interface{}{
+				"serviceName": config.Path,
+			}
+		case "httpupgrade":
+			outbound.StreamSettings.HTTPUpgradeSettings = map[string]interface{}{
+				"path": config.Path,
+				"host": config.Host,
+			}
 		}
 	}
 
 	return outbound
 }
 
-// saveXrayConfig 保存Xray配置到文件
-func (m *XrayProxyManager) saveXrayConfig(config *XrayConfig) error {
-	data, err := json.MarshalIndent(config, "", "  ")
+// buildCoreConfig 生成指定内核使用的配置：去掉内核不支持的功能，Xray还会合并自定义配置片段
+func (m *XrayProxyManager) buildCoreConfig(core Core, srv *server.ServerConfig, proxyCfg config.ProxyConfig) (*XrayConfig, error) {
+	xrayConfig, err := m.generateXrayConfig(srv, proxyCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate %s config: %w", core.Name(), err)
+	}
+
+	caps := core.Capabilities()
+	if !caps.hasFeature(FeatureHotSwitch) {
+		removeAPIConfig(xrayConfig)
+	}
+	if !caps.hasFeature(FeatureAccessLog) {
+		xrayConfig.Log.Access = ""
+	}
+	// 不使用Xray API的内核可以把API端口留给连接管理API
+	if caps.hasFeature(FeatureConnections) {
+		if xrayConfig.controller, err = newController(apiPort(proxyCfg.Inbound)); err != nil {
+			return nil, err
+		}
+	}
+
+	// 自定义片段使用Xray的配置格式
+	if core.Name() == config.CoreXray {
+		if err := applyConfigFragment(xrayConfig, proxyCfg.XrayConfig); err != nil {
+			return nil, err
+		}
+	}
+	return xrayConfig, nil
+}
+
+// prepareGeneratedConfig 生成内核配置并写入配置文件
+func (m *XrayProxyManager) prepareGeneratedConfig(core Core, srv *server.ServerConfig, proxyCfg config.ProxyConfig) (*XrayConfig, error) {
+	// 重置FakeDNS地址池
+	if err := m.fakeDNS.Reset(proxyCfg.FakeDNS); err != nil {
+		return nil, err
+	}
+
+	// 检查自定义规则所需的内核能力
+	if err := m.checkRoutingCapabilities(core, proxyCfg); err != nil {
+		return nil, err
+	}
+
+	xrayConfig, err := m.buildCoreConfig(core, srv, proxyCfg)
 	if err != nil {
-		return fmt.Errorf("failed to marshal xray config: %w", err)
+		return nil, err
+	}
+	if err := validateInbounds(xrayConfig.Inbounds); err != nil {
+		return nil, err
+	}
+
+	// 检查入站和连接管理API的端口是否被其他进程占用
+	if err := checkInboundPorts(listenPorts(xrayConfig), time.Second); err != nil {
+		return nil, err
+	}
+
+	if err := m.saveCoreConfig(core, xrayConfig, srv); err != nil {
+		return nil, err
+	}
+
+	m.resetAccessLog(xrayConfig.Log.Access, proxyCfg.AccessLog)
+	return xrayConfig, nil
+}
+
+// coreConfigFile 内核配置文件名
+func coreConfigFile(core string) string {
+	return core + "_config.json"
+}
+
+// saveCoreConfig 按内核格式生成配置并保存到文件
+func (m *XrayProxyManager) saveCoreConfig(core Core, model *XrayConfig, srv *server.ServerConfig) error {
+	data, err := core.GenerateConfig(model, srv)
+	if err != nil {
+		return err
 	}
 
 	if err := os.WriteFile(m.configPath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write xray config file: %w", err)
+		return fmt.Errorf("failed to write %s config file: %w", core.Name(), err)
 	}
 
 	return nil
-}
\ No newline at end of file
+}
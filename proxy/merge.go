@@ -0,0 +1,256 @@
+package proxy
+
+import (
+	"Gox/config"
+	"Gox/server"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// 数组合并方式
+const (
+	MergeAppend  = "append"  // 新元素追加到末尾
+	MergePrepend = "prepend" // 新元素插入到开头
+	MergeReplace = "replace" // 整个数组替换
+)
+
+const (
+	// mergeStrategyKey 数组合并指令中指定合并方式的字段
+	mergeStrategyKey = "_strategy"
+	// mergeItemsKey 数组合并指令中的元素字段
+	mergeItemsKey = "_items"
+)
+
+// applyConfigFragment 将用户提供的JSON片段深度合并到生成的配置中
+// 对象逐字段合并，null删除字段；数组中tag(规则为ruleTag)相同的元素合并，其余元素按合并方式插入
+// 数组可以写成 {"_strategy": "prepend", "_items": [...]} 指定合并方式，直接写数组时为append
+func applyConfigFragment(xrayConfig *XrayConfig, fragment string) error {
+	if strings.TrimSpace(fragment) == "" {
+		return nil
+	}
+
+	var overlay map[string]interface{}
+	if err := json.Unmarshal([]byte(fragment), &overlay); err != nil {
+		return &ConfigMergeError{Path: "$", Reason: fmt.Sprintf("invalid JSON object: %v", err)}
+	}
+
+	base, err := configDocument(xrayConfig)
+	if err != nil {
+		return err
+	}
+	merged, err := mergeValue(base, overlay, "$")
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return fmt.Errorf("failed to marshal merged xray config: %w", err)
+	}
+	var result XrayConfig
+	if err := json.Unmarshal(data, &result); err != nil {
+		return &ConfigMergeError{Path: "$", Reason: fmt.Sprintf("merged config is not a valid xray config: %v", err)}
+	}
+	result.raw = data
+	*xrayConfig = result
+	return nil
+}
+
+// ValidateXrayConfig 检查自定义Xray配置片段能否合并到按待保存的代理配置生成的配置中
+func (m *XrayProxyManager) ValidateXrayConfig(proxyCfg config.ProxyConfig) error {
+	if strings.TrimSpace(proxyCfg.XrayConfig) == "" {
+		return nil
+	}
+
+	// 未运行时用占位服务器生成配置，片段只与配置结构有关
+	srv := m.GetActiveServer()
+	if srv == nil {
+		srv = &server.ServerConfig{Protocol: "vmess", Address: "127.0.0.1", Port: 443}
+	}
+	xrayConfig, err := m.generateXrayConfig(srv, proxyCfg)
+	if err != nil {
+		return err
+	}
+	return applyConfigFragment(xrayConfig, proxyCfg.XrayConfig)
+}
+
+// configDocument 将配置转换为通用JSON对象，合并过的配置使用合并结果
+func configDocument(xrayConfig *XrayConfig) (map[string]interface{}, error) {
+	data := xrayConfig.raw
+	if data == nil {
+		var err error
+		if data, err = json.Marshal(xrayConfig); err != nil {
+			return nil, fmt.Errorf("failed to marshal xray config: %w", err)
+		}
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to decode xray config: %w", err)
+	}
+	return document, nil
+}
+
+// mergeValue 合并单个值
+func mergeValue(base, overlay interface{}, path string) (interface{}, error) {
+	switch overlayValue := overlay.(type) {
+	case map[string]interface{}:
+		if baseArray, ok := base.([]interface{}); ok {
+			strategy, items, err := mergeDirective(overlayValue, path)
+			if err != nil {
+				return nil, err
+			}
+			return mergeArray(baseArray, items, strategy, path)
+		}
+		baseObject, ok := base.(map[string]interface{})
+		if !ok {
+			if base != nil {
+				return nil, &ConfigMergeError{Path: path, Reason: fmt.Sprintf("cannot merge an object into %s", jsonKind(base))}
+			}
+			baseObject = map[string]interface{}{}
+		}
+		return mergeObject(baseObject, overlayValue, path)
+	case []interface{}:
+		if base == nil {
+			return overlayValue, nil
+		}
+		baseArray, ok := base.([]interface{})
+		if !ok {
+			return nil, &ConfigMergeError{Path: path, Reason: fmt.Sprintf("cannot merge an array into %s", jsonKind(base))}
+		}
+		return mergeArray(baseArray, overlayValue, MergeAppend, path)
+	default:
+		switch base.(type) {
+		case map[string]interface{}, []interface{}:
+			return nil, &ConfigMergeError{Path: path, Reason: fmt.Sprintf("cannot replace %s with %s", jsonKind(base), jsonKind(overlay))}
+		}
+		return overlay, nil
+	}
+}
+
+// mergeObject 逐字段合并对象，值为null的字段会被删除
+func mergeObject(base, overlay map[string]interface{}, path string) (map[string]interface{}, error) {
+	for key, value := range overlay {
+		childPath := path + "." + key
+		if value == nil {
+			delete(base, key)
+			continue
+		}
+		merged, err := mergeValue(base[key], value, childPath)
+		if err != nil {
+			return nil, err
+		}
+		base[key] = merged
+	}
+	return base, nil
+}
+
+// mergeDirective 解析数组合并指令
+func mergeDirective(directive map[string]interface{}, path string) (string, []interface{}, error) {
+	for key := range directive {
+		if key != mergeStrategyKey && key != mergeItemsKey {
+			return "", nil, &ConfigMergeError{
+				Path:   path,
+				Reason: fmt.Sprintf("array merge directive only accepts %s and %s, got %q", mergeStrategyKey, mergeItemsKey, key),
+			}
+		}
+	}
+
+	strategy := MergeAppend
+	if value, ok := directive[mergeStrategyKey]; ok {
+		name, ok := value.(string)
+		if !ok || (name != MergeAppend && name != MergePrepend && name != MergeReplace) {
+			return "", nil, &ConfigMergeError{
+				Path:   path,
+				Reason: fmt.Sprintf("unknown merge strategy %v, expected append, prepend or replace", value),
+			}
+		}
+		strategy = name
+	}
+
+	var items []interface{}
+	if value, ok := directive[mergeItemsKey]; ok {
+		if items, ok = value.([]interface{}); !ok {
+			return "", nil, &ConfigMergeError{Path: path, Reason: fmt.Sprintf("%s must be an array, got %s", mergeItemsKey, jsonKind(value))}
+		}
+	}
+	return strategy, items, nil
+}
+
+// mergeArray 合并数组，tag相同的元素深度合并，其余元素按合并方式插入
+func mergeArray(base, items []interface{}, strategy, path string) ([]interface{}, error) {
+	if strategy == MergeReplace {
+		return items, nil
+	}
+
+	seen := make(map[string]bool)
+	var added []interface{}
+	for i, item := range items {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		key := elementKey(item)
+		if key == "" {
+			added = append(added, item)
+			continue
+		}
+		if seen[key] {
+			return nil, &ConfigMergeError{Path: itemPath, Reason: fmt.Sprintf("duplicate element %s", key)}
+		}
+		seen[key] = true
+
+		index := -1
+		for j, existing := range base {
+			if elementKey(existing) == key {
+				index = j
+				break
+			}
+		}
+		if index < 0 {
+			added = append(added, item)
+			continue
+		}
+		merged, err := mergeValue(base[index], item, fmt.Sprintf("%s[%s]", path, key))
+		if err != nil {
+			return nil, err
+		}
+		base[index] = merged
+	}
+
+	if strategy == MergePrepend {
+		return append(added, base...), nil
+	}
+	return append(base, added...), nil
+}
+
+// elementKey 数组元素的合并键：tag，路由规则使用ruleTag
+func elementKey(value interface{}) string {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	for _, field := range []string{"tag", "ruleTag"} {
+		if key, ok := object[field].(string); ok && key != "" {
+			return field + "=" + key
+		}
+	}
+	return ""
+}
+
+// jsonKind JSON值的类型名称，用于错误信息
+func jsonKind(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "an array"
+	case string:
+		return "a string"
+	case float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
@@ -0,0 +1,326 @@
+package proxy
+
+import (
+	"Gox/config"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// privateIPv4Ranges geoip:private 对应的IPv4网段
+var privateIPv4Ranges = []string{
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+}
+
+// pacRule PAC脚本中的单条规则
+type pacRule struct {
+	Action  string          `json:"action"`
+	Full    map[string]bool `json:"full,omitempty"`
+	Suffix  map[string]bool `json:"suffix,omitempty"`
+	Keyword []string        `json:"keyword,omitempty"`
+	Regexp  []string        `json:"regexp,omitempty"`
+	CIDR    [][2]string     `json:"cidr,omitempty"`
+}
+
+// pacTemplate PAC脚本模板，先按域名匹配，未命中时再按IP匹配，与Xray的IPIfNonMatch一致
+const pacTemplate = `// Generated by Gox Client at %s
+var PROXY = %q;
+var RESOLVE = %t;
+var RULES = %s;
+
+function matchHost(rule, host) {
+  var i;
+  if (rule.full && rule.full.hasOwnProperty(host)) return true;
+  if (rule.suffix) {
+    var h = host;
+    while (true) {
+      if (rule.suffix.hasOwnProperty(h)) return true;
+      i = h.indexOf(".");
+      if (i < 0) break;
+      h = h.substring(i + 1);
+    }
+  }
+  if (rule.keyword) {
+    for (i = 0; i < rule.keyword.length; i++) {
+      if (host.indexOf(rule.keyword[i]) >= 0) return true;
+    }
+  }
+  if (rule.regexp) {
+    for (i = 0; i < rule.regexp.length; i++) {
+      if (new RegExp(rule.regexp[i]).test(host)) return true;
+    }
+  }
+  return false;
+}
+
+function matchIP(rule, ip) {
+  for (var i = 0; i < rule.cidr.length; i++) {
+    if (isInNet(ip, rule.cidr[i][0], rule.cidr[i][1])) return true;
+  }
+  return false;
+}
+
+function FindProxyForURL(url, host) {
+  var i;
+  host = host.toLowerCase();
+  if (isPlainHostName(host)) return "DIRECT";
+
+  var literal = /^\d+\.\d+\.\d+\.\d+$/.test(host);
+  if (!literal) {
+    for (i = 0; i < RULES.length; i++) {
+      if (matchHost(RULES[i], host)) return RULES[i].action;
+    }
+  }
+
+  var ip = literal ? host : (RESOLVE ? dnsResolve(host) : null);
+  if (ip) {
+    for (i = 0; i < RULES.length; i++) {
+      if (RULES[i].cidr && matchIP(RULES[i], ip)) return RULES[i].action;
+    }
+  }
+  return PROXY;
+}
+`
+
+// GeneratePAC 根据当前路由规则生成PAC脚本
+// 直连规则返回DIRECT，其余出站都交给HTTP入站，由Xray继续按同样的规则处理
+func (m *XrayProxyManager) GeneratePAC() ([]byte, error) {
+	return m.generatePAC(m.proxyConfig())
+}
+
+// generatePAC 生成PAC脚本
+func (m *XrayProxyManager) generatePAC(proxyCfg config.ProxyConfig) ([]byte, error) {
+	// 优先使用HTTP入站，其次是同样接受HTTP请求的混合入站
+	inbounds, err := m.generateInbounds(proxyCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	httpAddr := ""
+	for _, inbound := range inbounds {
+		if inbound.Tag == "http-in" || (inbound.Tag == "mixed-in" && httpAddr == "") {
+			httpAddr = net.JoinHostPort(pacProxyHost(inbound.Listen), strconv.Itoa(inbound.Port))
+		}
+	}
+	if httpAddr == "" {
+		return nil, fmt.Errorf("no http inbound available for pac")
+	}
+	proxy := "PROXY " + httpAddr
+
+	var rules []pacRule
+	for _, rule := range m.generateRoutingConfig(proxyCfg).Rules {
+		// 入站、端口、网络和进程条件无法在PAC中表达
+		if len(rule.InboundTag) > 0 || rule.Port != "" || rule.Network != "" || len(rule.Process) > 0 {
+			continue
+		}
+		if compiled, ok := compilePACRule(rule, proxy); ok {
+			rules = append(rules, compiled)
+		}
+	}
+	if rules == nil {
+		rules = []pacRule{}
+	}
+
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal pac rules: %w", err)
+	}
+
+	resolve := proxyCfg.RouteMode == config.RouteModeGeoIP
+	script := fmt.Sprintf(pacTemplate, time.Now().Format(time.RFC3339), proxy, resolve, data)
+	return []byte(script), nil
+}
+
+// compilePACRule 将Xray规则转换为PAC规则，geosite/geoip等数据文件条目无法在PAC中使用
+// 直连规则返回DIRECT，其余规则返回proxy；拦截规则同样交给代理，由Xray按同一规则丢弃连接
+func compilePACRule(rule RuleConfig, proxy string) (pacRule, bool) {
+	compiled := pacRule{Action: proxy}
+	if rule.OutboundTag == "direct" {
+		compiled.Action = "DIRECT"
+	}
+
+	for _, pattern := range rule.Domain {
+		switch {
+		case strings.HasPrefix(pattern, "geosite:"), strings.HasPrefix(pattern, "ext:"):
+		case strings.HasPrefix(pattern, "domain:"):
+			if compiled.Suffix == nil {
+				compiled.Suffix = make(map[string]bool)
+			}
+			compiled.Suffix[strings.ToLower(strings.TrimPrefix(pattern, "domain:"))] = true
+		case strings.HasPrefix(pattern, "full:"):
+			if compiled.Full == nil {
+				compiled.Full = make(map[string]bool)
+			}
+			compiled.Full[strings.ToLower(strings.TrimPrefix(pattern, "full:"))] = true
+		case strings.HasPrefix(pattern, "regexp:"):
+			compiled.Regexp = append(compiled.Regexp, strings.TrimPrefix(pattern, "regexp:"))
+		default:
+			compiled.Keyword = append(compiled.Keyword, strings.ToLower(strings.TrimPrefix(pattern, "keyword:")))
+		}
+	}
+
+	for _, pattern := range rule.IP {
+		cidrs := []string{pattern}
+		if pattern == "geoip:private" {
+			cidrs = privateIPv4Ranges
+		}
+		for _, cidr := range cidrs {
+			if !strings.Contains(cidr, "/") {
+				cidr += "/32"
+			}
+			_, ipNet, err := net.ParseCIDR(cidr)
+			if err != nil || ipNet.IP.To4() == nil {
+				continue
+			}
+			compiled.CIDR = append(compiled.CIDR, [2]string{ipNet.IP.String(), net.IP(ipNet.Mask).String()})
+		}
+	}
+
+	ok := len(compiled.Full) > 0 || len(compiled.Suffix) > 0 || len(compiled.Keyword) > 0 ||
+		len(compiled.Regexp) > 0 || len(compiled.CIDR) > 0
+	return compiled, ok
+}
+
+// pacProxyHost PAC中使用的代理地址，监听全部地址时使用回环地址
+func pacProxyHost(listen string) string {
+	if listen == "" || listen == "0.0.0.0" || listen == "::" {
+		return "127.0.0.1"
+	}
+	return listen
+}
+
+// RefreshPAC 重新生成PAC脚本，并按配置启动或停止PAC服务
+func (m *XrayProxyManager) RefreshPAC() error {
+	return m.refreshPAC(m.proxyConfig(), m.IsRunning())
+}
+
+// refreshPAC 重新生成PAC脚本（不加锁），代理未运行时不提供PAC
+func (m *XrayProxyManager) refreshPAC(proxyCfg config.ProxyConfig, running bool) error {
+	if !proxyCfg.PAC.Enabled || !running {
+		return m.pac.Stop()
+	}
+
+	content, err := m.generatePAC(proxyCfg)
+	if err != nil {
+		return err
+	}
+	m.pac.Update(content)
+	return m.pac.Start(pacPort(proxyCfg.PAC))
+}
+
+// GetPACURL 获取PAC地址，PAC服务未运行时返回空字符串
+func (m *XrayProxyManager) GetPACURL() string {
+	return m.pac.URL()
+}
+
+// pacPort 获取PAC服务端口
+func pacPort(cfg config.PACConfig) int {
+	if cfg.Port <= 0 {
+		return config.DefaultPACPort
+	}
+	return cfg.Port
+}
+
+// PACServer 本地PAC文件服务
+type PACServer struct {
+	mu      sync.RWMutex
+	content []byte
+	server  *http.Server
+	addr    string
+}
+
+// NewPACServer 创建PAC服务
+func NewPACServer() *PACServer {
+	return &PACServer{}
+}
+
+// Update 更新PAC脚本内容
+func (s *PACServer) Update(content []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.content = content
+}
+
+// Start 在本地回环地址启动PAC服务，端口变化时会重新监听
+func (s *PACServer) Start(port int) error {
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.server != nil {
+		if s.addr == addr {
+			return nil
+		}
+		s.server.Close()
+		s.server = nil
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on pac port %d: %w", port, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handlePAC)
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("PAC server stopped with error: %v\n", err)
+		}
+	}()
+
+	s.server = server
+	s.addr = addr
+	return nil
+}
+
+// Stop 停止PAC服务
+func (s *PACServer) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.server == nil {
+		return nil
+	}
+	err := s.server.Close()
+	s.server = nil
+	s.addr = ""
+	return err
+}
+
+// URL 获取PAC地址，服务未运行时返回空字符串
+func (s *PACServer) URL() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.server == nil {
+		return ""
+	}
+	return "http://" + s.addr + "/proxy.pac"
+}
+
+// handlePAC 返回PAC脚本
+func (s *PACServer) handlePAC(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	content := s.content
+	s.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/x-ns-proxy-autoconfig")
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, "proxy.pac", time.Time{}, bytes.NewReader(content))
+}
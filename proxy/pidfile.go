@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// pidFileName 内核进程PID文件名
+const pidFileName = "xray.pid"
+
+// pidFilePath PID文件路径
+func (m *XrayProxyManager) pidFilePath() string {
+	return filepath.Join(m.workDir, pidFileName)
+}
+
+// writePIDFile 记录内核进程PID和二进制路径，应用异常退出后下次启动时据此清理
+func (m *XrayProxyManager) writePIDFile(proc *coreProcess) {
+	if proc.cmd.Process == nil {
+		return
+	}
+	content := strconv.Itoa(proc.cmd.Process.Pid) + "\n" + proc.cmd.Path
+	if err := os.WriteFile(m.pidFilePath(), []byte(content), 0644); err != nil {
+		fmt.Printf("Failed to write core pid file: %v\n", err)
+	}
+}
+
+// removePIDFile 删除PID文件
+func (m *XrayProxyManager) removePIDFile() {
+	if err := os.Remove(m.pidFilePath()); err != nil && !os.IsNotExist(err) {
+		fmt.Printf("Failed to remove core pid file: %v\n", err)
+	}
+}
+
+// cleanupOrphan 结束PID文件中记录的遗留内核进程
+// 只有进程名与记录的内核一致时才会结束，避免误杀复用了该PID的其他进程
+func (m *XrayProxyManager) cleanupOrphan() error {
+	data, err := os.ReadFile(m.pidFilePath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read core pid file: %w", err)
+	}
+	defer m.removePIDFile()
+
+	// 旧版本的PID文件只有PID一行，此时按Xray处理
+	pidLine, binaryPath, _ := strings.Cut(strings.TrimSpace(string(data)), "\n")
+	pid, err := strconv.Atoi(strings.TrimSpace(pidLine))
+	if err != nil || pid <= 0 {
+		return nil
+	}
+	binaryPath = strings.TrimSpace(binaryPath)
+	if binaryPath == "" {
+		binaryPath = m.xrayPath
+	}
+
+	name := processName(pid)
+	if name == "" || !isCoreProcessName(name, binaryPath) {
+		return nil
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return nil
+	}
+	if err := process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("failed to kill orphan core process %d: %w", pid, err)
+	}
+	fmt.Printf("Killed orphan core process %d (%s)\n", pid, name)
+	return nil
+}
+
+// isCoreProcessName 检查进程名是否与内核二进制一致
+func isCoreProcessName(name, binaryPath string) bool {
+	normalize := func(s string) string {
+		return strings.TrimSuffix(strings.ToLower(filepath.Base(s)), ".exe")
+	}
+	return normalize(name) == normalize(binaryPath)
+}
@@ -0,0 +1,48 @@
+//go:build !linux && !windows
+
+package proxy
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// isAddrInUse 检查监听错误是否为端口被占用
+func isAddrInUse(err error) bool {
+	return errors.Is(err, syscall.EADDRINUSE)
+}
+
+// findPortOwner 通过lsof查找监听指定TCP端口的进程
+func findPortOwner(port int) (int, string) {
+	output, err := exec.Command("lsof", "-nP", "-iTCP:"+strconv.Itoa(port), "-sTCP:LISTEN", "-Fpc").Output()
+	if err != nil {
+		return 0, ""
+	}
+
+	pid, name := 0, ""
+	for _, line := range strings.Split(string(output), "\n") {
+		switch {
+		case strings.HasPrefix(line, "p") && pid == 0:
+			pid, _ = strconv.Atoi(line[1:])
+		case strings.HasPrefix(line, "c") && name == "":
+			name = line[1:]
+		}
+	}
+	return pid, name
+}
+
+// processName 通过ps获取进程名，进程不存在时返回空字符串
+func processName(pid int) string {
+	output, err := exec.Command("ps", "-p", strconv.Itoa(pid), "-o", "comm=").Output()
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(strings.TrimSpace(string(output)))
+}
+
+// hideWindow 只有Windows会为子进程弹出控制台窗口
+func hideWindow(cmd *exec.Cmd) {}
@@ -0,0 +1,71 @@
+//go:build windows
+
+package proxy
+
+import (
+	"encoding/csv"
+	"errors"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// wsaeAddrInUse Windows下端口被占用的错误码
+const wsaeAddrInUse = syscall.Errno(10048)
+
+// isAddrInUse 检查监听错误是否为端口被占用
+func isAddrInUse(err error) bool {
+	return errors.Is(err, wsaeAddrInUse) || errors.Is(err, syscall.EADDRINUSE)
+}
+
+// findPortOwner 通过netstat和tasklist查找监听指定TCP端口的进程
+func findPortOwner(port int) (int, string) {
+	output, err := hiddenCommand("netstat", "-ano", "-p", "tcp").Output()
+	if err != nil {
+		return 0, ""
+	}
+
+	suffix := ":" + strconv.Itoa(port)
+	pid := 0
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		// fields: Proto LocalAddress ForeignAddress State PID
+		if len(fields) < 5 || fields[3] != "LISTENING" || !strings.HasSuffix(fields[1], suffix) {
+			continue
+		}
+		if pid, err = strconv.Atoi(fields[4]); err == nil {
+			break
+		}
+	}
+	if pid == 0 {
+		return 0, ""
+	}
+	return pid, processName(pid)
+}
+
+// processName 通过tasklist获取进程名，进程不存在时返回空字符串
+func processName(pid int) string {
+	output, err := hiddenCommand("tasklist", "/FI", "PID eq "+strconv.Itoa(pid), "/FO", "CSV", "/NH").Output()
+	if err != nil {
+		return ""
+	}
+	// 进程不存在时tasklist输出提示信息而不是CSV
+	record, err := csv.NewReader(strings.NewReader(string(output))).Read()
+	if err != nil || len(record) < 2 || record[1] != strconv.Itoa(pid) {
+		return ""
+	}
+	return record[0]
+}
+
+// hiddenCommand 创建不弹出控制台窗口的命令
+func hiddenCommand(name string, args ...string) *exec.Cmd {
+	cmd := exec.Command(name, args...)
+	hideWindow(cmd)
+	return cmd
+}
+
+// hideWindow 让命令运行时不弹出控制台窗口
+func hideWindow(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+}
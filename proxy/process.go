@@ -0,0 +1,261 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// startupTimeout 等待内核就绪的最长时间
+	startupTimeout = 10 * time.Second
+	// stopTimeout 发送中断信号后等待内核退出的最长时间
+	stopTimeout = 3 * time.Second
+	// outputTailLines 保留的内核输出行数
+	outputTailLines = 50
+)
+
+// StartupError 内核启动失败，携带捕获到的输出
+type StartupError struct {
+	Core     string // 内核名称
+	Reason   string // 失败原因
+	ExitCode int    // 退出码，进程未退出时为-1
+	Stderr   string // 捕获的stderr末尾内容
+	Stdout   string // 捕获的stdout末尾内容
+}
+
+// Error 实现error接口
+func (e *StartupError) Error() string {
+	detail := strings.TrimSpace(e.Stderr)
+	if detail == "" {
+		detail = strings.TrimSpace(e.Stdout)
+	}
+	if idx := strings.LastIndex(detail, "\n"); idx >= 0 {
+		detail = detail[idx+1:]
+	}
+	if detail == "" {
+		return fmt.Sprintf("%s failed to start: %s", e.Core, e.Reason)
+	}
+	return fmt.Sprintf("%s failed to start: %s: %s", e.Core, e.Reason, detail)
+}
+
+// coreProcess 运行中的内核进程
+type coreProcess struct {
+	cmd    *exec.Cmd
+	name   string
+	stdout *outputTail
+	stderr *outputTail
+	// isReady 判断一行输出是否表示启动完成
+	isReady func(string) bool
+	// started 在输出中出现启动完成日志时关闭
+	started     chan struct{}
+	startedOnce sync.Once
+	// done 在进程退出后关闭，之后可读取err
+	done chan struct{}
+	err  error
+}
+
+// startCoreProcess 启动内核进程并收集输出
+func startCoreProcess(cmd *exec.Cmd, core Core) (*coreProcess, error) {
+	proc := &coreProcess{
+		cmd:     cmd,
+		name:    core.Name(),
+		stdout:  newOutputTail(outputTailLines),
+		stderr:  newOutputTail(outputTailLines),
+		isReady: core.IsReadyLine,
+		started: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	proc.cmd.Stdout = &lineWriter{onLine: proc.handleStdout}
+	proc.cmd.Stderr = &lineWriter{onLine: proc.handleStderr}
+
+	if err := proc.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s process: %w", proc.name, err)
+	}
+
+	go func() {
+		proc.err = proc.cmd.Wait()
+		close(proc.done)
+	}()
+	return proc, nil
+}
+
+// handleStdout 处理一行stdout输出
+func (p *coreProcess) handleStdout(line string) {
+	p.stdout.Add(line)
+	logCoreLine(line, zapcore.InfoLevel)
+	p.checkReady(line)
+}
+
+// checkReady 出现启动完成日志时标记进程已就绪
+func (p *coreProcess) checkReady(line string) {
+	if p.isReady != nil && p.isReady(line) {
+		p.startedOnce.Do(func() { close(p.started) })
+	}
+}
+
+// handleStderr 处理一行stderr输出，没有级别的内容按错误记录
+func (p *coreProcess) handleStderr(line string) {
+	p.stderr.Add(line)
+	logCoreLine(line, zapcore.ErrorLevel)
+	// sing-box将日志输出到stderr
+	p.checkReady(line)
+}
+
+// stop 先发送中断信号让内核正常退出，超时后强制结束
+func (p *coreProcess) stop(timeout time.Duration) error {
+	if p.cmd.Process == nil {
+		return nil
+	}
+	select {
+	case <-p.done:
+		return nil
+	default:
+	}
+
+	// Windows不支持发送中断信号，此时直接结束进程
+	if err := p.cmd.Process.Signal(os.Interrupt); err == nil {
+		select {
+		case <-p.done:
+			return nil
+		case <-time.After(timeout):
+		}
+	}
+
+	if err := p.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("failed to kill %s process: %w", p.name, err)
+	}
+	select {
+	case <-p.done:
+	case <-time.After(timeout):
+	}
+	return nil
+}
+
+// exitCode 获取退出码，进程未退出时返回-1
+func (p *coreProcess) exitCode() int {
+	select {
+	case <-p.done:
+	default:
+		return -1
+	}
+	var exitErr *exec.ExitError
+	if errors.As(p.err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	if p.cmd.ProcessState != nil {
+		return p.cmd.ProcessState.ExitCode()
+	}
+	return -1
+}
+
+// startupError 生成携带输出的启动错误
+func (p *coreProcess) startupError(reason string) *StartupError {
+	return &StartupError{
+		Core:     p.name,
+		Reason:   reason,
+		ExitCode: p.exitCode(),
+		Stderr:   p.stderr.String(),
+		Stdout:   p.stdout.String(),
+	}
+}
+
+// waitForReady 等待内核就绪：出现启动日志或入站端口可以连接
+// 进程提前退出或超时时返回StartupError
+func waitForReady(proc *coreProcess, inbounds []InboundConfig, timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-proc.done:
+			return proc.startupError(fmt.Sprintf("process exited with code %d", proc.exitCode()))
+		case <-proc.started:
+			return nil
+		case <-ticker.C:
+			if inboundsReachable(inbounds) {
+				return nil
+			}
+		case <-timer.C:
+			return proc.startupError(fmt.Sprintf("not ready after %s", timeout))
+		}
+	}
+}
+
+// inboundsReachable 检查所有入站端口是否都已可以连接
+func inboundsReachable(inbounds []InboundConfig) bool {
+	if len(inbounds) == 0 {
+		return false
+	}
+	for _, inbound := range inbounds {
+		addr := net.JoinHostPort(pacProxyHost(inbound.Listen), strconv.Itoa(inbound.Port))
+		conn, err := net.DialTimeout("tcp", addr, 200*time.Millisecond)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+	}
+	return true
+}
+
+// outputTail 保留最近若干行输出
+type outputTail struct {
+	mu    sync.Mutex
+	lines []string
+	limit int
+}
+
+// newOutputTail 创建输出缓冲
+func newOutputTail(limit int) *outputTail {
+	return &outputTail{limit: limit}
+}
+
+// Add 追加一行输出
+func (t *outputTail) Add(line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lines = append(t.lines, line)
+	if len(t.lines) > t.limit {
+		t.lines = t.lines[len(t.lines)-t.limit:]
+	}
+}
+
+// String 返回保留的输出
+func (t *outputTail) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return strings.Join(t.lines, "\n")
+}
+
+// lineWriter 将写入的数据按行回调
+// exec为每个输出流使用单独的goroutine写入，因此无需加锁
+type lineWriter struct {
+	buf    []byte
+	onLine func(string)
+}
+
+// Write 实现io.Writer接口
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx < 0 {
+			break
+		}
+		line := strings.TrimRight(string(w.buf[:idx]), "\r")
+		w.buf = w.buf[idx+1:]
+		w.onLine(line)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,327 @@
+package proxy
+
+import (
+	"Gox/config"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RouteTestResult 路由测试结果
+type RouteTestResult struct {
+	Target      string   `json:"target"`      // 测试目标
+	Domain      string   `json:"domain"`      // 参与匹配的域名
+	IP          string   `json:"ip"`          // 参与匹配的IP
+	Port        int      `json:"port"`        // 目标端口
+	FakeIP      bool     `json:"fakeIP"`      // IP是否来自FakeDNS地址池
+	OutboundTag string   `json:"outboundTag"` // 命中的出站
+	RuleIndex   int      `json:"ruleIndex"`   // 命中规则序号，-1表示未命中任何规则
+	Rule        string   `json:"rule"`        // 命中规则描述
+	Skipped     []string `json:"skipped"`     // 无法在本地判定的条件
+}
+
+// generateRoutingConfig 生成路由配置
+func (m *XrayProxyManager) generateRoutingConfig(proxyCfg config.ProxyConfig) RoutingConfig {
+	var rules []RuleConfig
+
+	// FakeDNS需要劫持DNS查询交给内置DNS处理
+	if proxyCfg.FakeDNS.Enabled {
+		rules = append(rules,
+			RuleConfig{
+				Type:        "field",
+				OutboundTag: "dns-out",
+				InboundTag:  []string{"dns-in"},
+			},
+			RuleConfig{
+				Type:        "field",
+				OutboundTag: "dns-out",
+				Port:        "53",
+				Network:     "udp",
+			},
+		)
+	}
+
+	// 自定义规则优先于默认分流规则
+	following := append(compileRoutingRules(proxyCfg.RoutingRules),
+		RuleConfig{
+			Type:        "field",
+			OutboundTag: "direct",
+			Domain:      []string{"geosite:cn"},
+		},
+		RuleConfig{
+			Type:        "field",
+			OutboundTag: "direct",
+			IP:          []string{"geoip:cn", "geoip:private"},
+		},
+	)
+
+	// 拦截列表优先于分流规则，白名单又优先于拦截列表
+	blockRules, _ := m.compileBlockLists(proxyCfg)
+	rules = append(rules, allowlistRules(proxyCfg, blockRules, following)...)
+	rules = append(rules, blockRules...)
+	rules = append(rules, following...)
+
+	return RoutingConfig{
+		DomainStrategy: "IPIfNonMatch",
+		Rules:          rules,
+	}
+}
+
+// TestRoute 测试目标地址会命中的路由规则
+// 目标可以是域名、IP、host:port或URL；FakeDNS地址池内的IP会先反查回域名
+func (m *XrayProxyManager) TestRoute(target string) (*RouteTestResult, error) {
+	host, port, err := parseRouteTarget(target)
+	if err != nil {
+		return nil, err
+	}
+
+	proxyCfg := m.proxyConfig()
+	if err := m.fakeDNS.Reset(proxyCfg.FakeDNS); err != nil {
+		return nil, err
+	}
+
+	result := &RouteTestResult{
+		Target:    target,
+		Port:      port,
+		RuleIndex: -1,
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		result.IP = ip.String()
+		if proxyCfg.FakeDNS.Enabled && m.fakeDNS.Contains(ip) {
+			result.FakeIP = true
+			if domain, ok := m.fakeDNS.Lookup(ip); ok {
+				result.Domain = domain
+			} else {
+				result.Skipped = append(result.Skipped, fmt.Sprintf("fake ip %s has no known domain", ip))
+			}
+		}
+	} else {
+		result.Domain = strings.ToLower(strings.TrimSuffix(host, "."))
+		if proxyCfg.FakeDNS.Enabled && m.IsRunning() {
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+			if ips, err := m.fakeDNS.Resolve(ctx, result.Domain); err == nil && len(ips) > 0 {
+				result.IP = ips[0].String()
+				result.FakeIP = m.fakeDNS.Contains(ips[0])
+			}
+		}
+	}
+
+	routing := m.generateRoutingConfig(proxyCfg)
+	for i, rule := range routing.Rules {
+		matched, skipped := matchRule(rule, result)
+		for _, condition := range skipped {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("rule #%d: %s", i, condition))
+		}
+		if matched {
+			result.RuleIndex = i
+			result.Rule = describeRule(rule)
+			result.OutboundTag = rule.OutboundTag
+			return result, nil
+		}
+	}
+
+	// 未命中任何规则时使用第一个出站
+	result.OutboundTag = "proxy"
+	return result, nil
+}
+
+// parseRouteTarget 解析路由测试目标
+func parseRouteTarget(target string) (string, int, error) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return "", 0, fmt.Errorf("route test target is empty")
+	}
+
+	if strings.Contains(target, "://") {
+		u, err := url.Parse(target)
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid route test target %s: %w", target, err)
+		}
+		port := 80
+		if u.Scheme == "https" {
+			port = 443
+		}
+		if p := u.Port(); p != "" {
+			port, _ = strconv.Atoi(p)
+		}
+		return u.Hostname(), port, nil
+	}
+
+	if host, p, err := net.SplitHostPort(target); err == nil {
+		port, err := strconv.Atoi(p)
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid port in route test target %s", target)
+		}
+		return host, port, nil
+	}
+
+	return strings.Trim(target, "[]"), 0, nil
+}
+
+// matchRule 按Xray语义匹配规则：各字段之间为与，字段内部为或
+// 返回是否命中以及本地无法判定的条件
+func matchRule(rule RuleConfig, result *RouteTestResult) (bool, []string) {
+	var skipped []string
+
+	if len(rule.InboundTag) > 0 {
+		return false, nil
+	}
+	if len(rule.Process) > 0 {
+		// 路由测试没有来源进程信息
+		return false, []string{"process:" + summarizeValues(rule.Process)}
+	}
+	if rule.Network != "" && !strings.Contains(rule.Network, "tcp") {
+		return false, nil
+	}
+	if rule.Port != "" && !matchPort(rule.Port, result.Port) {
+		return false, nil
+	}
+
+	if len(rule.Domain) > 0 {
+		if result.Domain == "" {
+			return false, nil
+		}
+		matched := false
+		for _, pattern := range rule.Domain {
+			ok, evaluable := matchDomain(pattern, result.Domain)
+			if !evaluable {
+				skipped = append(skipped, pattern)
+				continue
+			}
+			if ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false, skipped
+		}
+	}
+
+	if len(rule.IP) > 0 {
+		// 假IP只在Xray内部有意义，不参与IP规则匹配
+		ip := net.ParseIP(result.IP)
+		if ip == nil || result.FakeIP {
+			return false, skipped
+		}
+		matched := false
+		for _, pattern := range rule.IP {
+			ok, evaluable := matchIP(pattern, ip)
+			if !evaluable {
+				skipped = append(skipped, pattern)
+				continue
+			}
+			if ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false, skipped
+		}
+	}
+
+	return true, skipped
+}
+
+// matchDomain 匹配单个域名条件，第二个返回值表示能否在本地判定
+func matchDomain(pattern, domain string) (bool, bool) {
+	switch {
+	case strings.HasPrefix(pattern, "geosite:"), strings.HasPrefix(pattern, "ext:"):
+		return false, false
+	case strings.HasPrefix(pattern, "regexp:"):
+		re, err := regexp.Compile(strings.TrimPrefix(pattern, "regexp:"))
+		if err != nil {
+			return false, false
+		}
+		return re.MatchString(domain), true
+	case strings.HasPrefix(pattern, "domain:"):
+		suffix := strings.ToLower(strings.TrimPrefix(pattern, "domain:"))
+		return domain == suffix || strings.HasSuffix(domain, "."+suffix), true
+	case strings.HasPrefix(pattern, "full:"):
+		return domain == strings.ToLower(strings.TrimPrefix(pattern, "full:")), true
+	case strings.HasPrefix(pattern, "keyword:"):
+		return strings.Contains(domain, strings.ToLower(strings.TrimPrefix(pattern, "keyword:"))), true
+	default:
+		return strings.Contains(domain, strings.ToLower(pattern)), true
+	}
+}
+
+// matchIP 匹配单个IP条件，第二个返回值表示能否在本地判定
+func matchIP(pattern string, ip net.IP) (bool, bool) {
+	if pattern == "geoip:private" {
+		return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast(), true
+	}
+	if strings.HasPrefix(pattern, "geoip:") || strings.HasPrefix(pattern, "ext:") {
+		return false, false
+	}
+	if _, ipNet, err := net.ParseCIDR(pattern); err == nil {
+		return ipNet.Contains(ip), true
+	}
+	if other := net.ParseIP(pattern); other != nil {
+		return other.Equal(ip), true
+	}
+	return false, false
+}
+
+// matchPort 匹配端口条件，支持 "53"、"1000-2000" 和 "80,443" 形式
+func matchPort(spec string, port int) bool {
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if from, to, ok := strings.Cut(part, "-"); ok {
+			low, err1 := strconv.Atoi(from)
+			high, err2 := strconv.Atoi(to)
+			if err1 == nil && err2 == nil && port >= low && port <= high {
+				return true
+			}
+			continue
+		}
+		if p, err := strconv.Atoi(part); err == nil && p == port {
+			return true
+		}
+	}
+	return false
+}
+
+// describeRule 生成规则的可读描述
+func describeRule(rule RuleConfig) string {
+	var parts []string
+	if rule.RuleTag != "" {
+		parts = append(parts, "["+rule.RuleTag+"]")
+	}
+	if len(rule.InboundTag) > 0 {
+		parts = append(parts, "inbound:"+strings.Join(rule.InboundTag, ","))
+	}
+	if len(rule.Domain) > 0 {
+		parts = append(parts, "domain:"+summarizeValues(rule.Domain))
+	}
+	if len(rule.IP) > 0 {
+		parts = append(parts, "ip:"+summarizeValues(rule.IP))
+	}
+	if len(rule.Process) > 0 {
+		parts = append(parts, "process:"+summarizeValues(rule.Process))
+	}
+	if rule.Port != "" {
+		parts = append(parts, "port:"+rule.Port)
+	}
+	if rule.Network != "" {
+		parts = append(parts, "network:"+rule.Network)
+	}
+	return strings.Join(parts, " ") + " -> " + rule.OutboundTag
+}
+
+// summarizeValues 拼接规则条目，条目过多时只显示前几项
+func summarizeValues(values []string) string {
+	const maxShown = 5
+	if len(values) <= maxShown {
+		return strings.Join(values, ",")
+	}
+	return fmt.Sprintf("%s,...(+%d)", strings.Join(values[:maxShown], ","), len(values)-maxShown)
+}
@@ -0,0 +1,159 @@
+package proxy
+
+import (
+	"Gox/config"
+	"fmt"
+	"net"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// processRuleMinVersion Xray开始支持process路由条件的版本
+const processRuleMinVersion = "25.8.3"
+
+// validOutboundTags 自定义规则允许使用的出站
+var validOutboundTags = map[string]bool{
+	"proxy":  true,
+	"direct": true,
+	"block":  true,
+}
+
+// ValidateRoutingRule 校验自定义路由规则，进程规则还会检查设置中选择的内核和平台能力
+func (m *XrayProxyManager) ValidateRoutingRule(rule config.RoutingRuleConfig) error {
+	if !validOutboundTags[rule.OutboundTag] {
+		return fmt.Errorf("规则 '%s' 的出站 '%s' 无效", rule.Name, rule.OutboundTag)
+	}
+
+	values := cleanRuleValues(rule.Values)
+	if len(values) == 0 {
+		return fmt.Errorf("规则 '%s' 没有匹配条目", rule.Name)
+	}
+
+	switch rule.Type {
+	case config.RuleTypeDomain:
+		for _, value := range values {
+			if pattern, ok := strings.CutPrefix(value, "regexp:"); ok {
+				if _, err := regexp.Compile(pattern); err != nil {
+					return fmt.Errorf("规则 '%s' 的正则 '%s' 无效: %w", rule.Name, pattern, err)
+				}
+			}
+		}
+	case config.RuleTypeIP:
+		for _, value := range values {
+			if strings.HasPrefix(value, "geoip:") || strings.HasPrefix(value, "ext:") {
+				continue
+			}
+			if _, _, err := net.ParseCIDR(value); err != nil && net.ParseIP(value) == nil {
+				return fmt.Errorf("规则 '%s' 的IP '%s' 无效", rule.Name, value)
+			}
+		}
+	case config.RuleTypeProcess:
+		// 按设置中选择的内核检查，sing-box不受Xray的平台和版本限制
+		proxyCfg := m.proxyConfig()
+		name := proxyCfg.Core
+		if name == "" {
+			name = config.CoreXray
+		}
+		core, ok := m.cores[name]
+		if !ok {
+			return fmt.Errorf("unknown core %s", name)
+		}
+		rule.Enabled = true
+		proxyCfg.RoutingRules = []config.RoutingRuleConfig{rule}
+		if err := m.checkRoutingCapabilities(core, proxyCfg); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("规则 '%s' 的类型 '%s' 无效", rule.Name, rule.Type)
+	}
+
+	return nil
+}
+
+// checkProcessRuleSupport 检查当前平台和内核是否支持进程路由
+func (m *XrayProxyManager) checkProcessRuleSupport() error {
+	if runtime.GOOS != "windows" && runtime.GOOS != "linux" {
+		return &CapabilityError{
+			Feature: "process routing",
+			Reason:  fmt.Sprintf("platform %s is not supported by xray", runtime.GOOS),
+		}
+	}
+
+	version, err := m.coreVersion()
+	if err != nil {
+		return &CapabilityError{
+			Feature: "process routing",
+			Reason:  fmt.Sprintf("cannot determine xray version: %v", err),
+		}
+	}
+	if compareVersions(version, processRuleMinVersion) < 0 {
+		return &CapabilityError{
+			Feature: "process routing",
+			Reason:  fmt.Sprintf("xray %s is older than %s", version, processRuleMinVersion),
+		}
+	}
+
+	return nil
+}
+
+// checkRoutingCapabilities 检查启用的自定义规则是否都能被指定内核执行
+func (m *XrayProxyManager) checkRoutingCapabilities(core Core, proxyCfg config.ProxyConfig) error {
+	for _, rule := range proxyCfg.RoutingRules {
+		if !rule.Enabled || rule.Type != config.RuleTypeProcess {
+			continue
+		}
+		if !core.Capabilities().hasFeature(FeatureProcessRule) {
+			return &CapabilityError{
+				Feature: "process routing",
+				Reason:  fmt.Sprintf("%s core does not support it", core.Name()),
+			}
+		}
+		// Xray对平台和版本有额外要求
+		if core.Name() == config.CoreXray {
+			return m.checkProcessRuleSupport()
+		}
+		return nil
+	}
+	return nil
+}
+
+// compileRoutingRules 将自定义规则转换为Xray路由规则
+func compileRoutingRules(rules []config.RoutingRuleConfig) []RuleConfig {
+	var compiled []RuleConfig
+	for _, rule := range rules {
+		values := cleanRuleValues(rule.Values)
+		if !rule.Enabled || len(values) == 0 {
+			continue
+		}
+
+		ruleConfig := RuleConfig{
+			Type:        "field",
+			RuleTag:     "rule-" + rule.ID,
+			OutboundTag: rule.OutboundTag,
+		}
+		switch rule.Type {
+		case config.RuleTypeDomain:
+			ruleConfig.Domain = values
+		case config.RuleTypeIP:
+			ruleConfig.IP = values
+		case config.RuleTypeProcess:
+			ruleConfig.Process = values
+		default:
+			continue
+		}
+		compiled = append(compiled, ruleConfig)
+	}
+	return compiled
+}
+
+// cleanRuleValues 去除空白条目
+func cleanRuleValues(values []string) []string {
+	cleaned := make([]string, 0, len(values))
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			cleaned = append(cleaned, value)
+		}
+	}
+	return cleaned
+}
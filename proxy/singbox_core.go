@@ -0,0 +1,505 @@
+package proxy
+
+import (
+	"Gox/config"
+	"Gox/server"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// singBoxGeositeURL sing-geosite规则集下载地址
+	singBoxGeositeURL = "https://raw.githubusercontent.com/SagerNet/sing-geosite/rule-set/geosite-%s.srs"
+	// singBoxGeoIPURL sing-geoip规则集下载地址
+	singBoxGeoIPURL = "https://raw.githubusercontent.com/SagerNet/sing-geoip/rule-set/geoip-%s.srs"
+)
+
+// singBoxSpec sing-box二进制查找规则
+var singBoxSpec = binarySpec{
+	name:           "sing-box",
+	versionPattern: singBoxVersionPattern,
+}
+
+// singBoxCore sing-box内核，将XrayConfig转换为sing-box配置
+// 需要sing-box 1.11及以上版本（使用路由规则动作）
+type singBoxCore struct {
+	workDir   string
+	resources embed.FS
+
+	mu         sync.Mutex
+	info       *CoreInfo
+	configured string
+}
+
+// newSingBoxCore 创建sing-box内核，二进制在首次使用时查找
+func newSingBoxCore(workDir string, resources embed.FS) *singBoxCore {
+	return &singBoxCore{workDir: workDir, resources: resources}
+}
+
+// Name 内核名称
+func (c *singBoxCore) Name() string {
+	return config.CoreSingBox
+}
+
+// Binary 查找sing-box二进制，配置的路径变化时重新查找
+func (c *singBoxCore) Binary() (*CoreInfo, error) {
+	configured := config.GetDefaultConfig().Proxy.SingBoxBinaryPath
+	if cfg := config.GetConfig(); cfg != nil {
+		configured = cfg.Proxy.SingBoxBinaryPath
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.info != nil && c.configured == configured {
+		return c.info, nil
+	}
+	info, err := resolveCoreBinary(c.workDir, c.resources, configured, singBoxSpec)
+	if err != nil {
+		return nil, err
+	}
+	c.info = info
+	c.configured = configured
+	return info, nil
+}
+
+// Capabilities sing-box内核能力
+func (c *singBoxCore) Capabilities() CoreCapabilities {
+	caps := CoreCapabilities{
+		Core:       config.CoreSingBox,
+		Protocols:  []string{"vmess", "vless", "trojan", "shadowsocks", "hysteria2", "tuic", server.ProtocolCustom},
+		Transports: []string{"tcp", "ws", "h2", "grpc", "httpupgrade"},
+		Features:   []string{FeatureProcessRule, FeatureConnections},
+	}
+	info, err := c.Binary()
+	if err != nil {
+		caps.Error = err.Error()
+		return caps
+	}
+	caps.Available = true
+	caps.Version = info.Version
+	return caps
+}
+
+// Command 生成sing-box启动命令
+func (c *singBoxCore) Command(ctx context.Context, binary, configPath string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, binary, "run", "-c", configPath)
+	hideWindow(cmd)
+	return cmd
+}
+
+// TestConfig 使用sing-box check检查配置文件是否有效
+func (c *singBoxCore) TestConfig(binary, configPath string) error {
+	return runConfigTest(config.CoreSingBox, binary, "check", "-c", configPath)
+}
+
+// IsReadyLine sing-box启动完成后输出 "sing-box started (0.12s)"
+func (c *singBoxCore) IsReadyLine(line string) bool {
+	return strings.Contains(line, "sing-box started")
+}
+
+// GenerateConfig 将XrayConfig转换为sing-box配置
+// API、DNS查询口等Xray专用的入站会被忽略，发往block的规则转换为reject动作
+func (c *singBoxCore) GenerateConfig(model *XrayConfig, srv *server.ServerConfig) ([]byte, error) {
+	outbound, err := singBoxOutbound(srv)
+	if err != nil {
+		return nil, err
+	}
+
+	var inbounds []map[string]interface{}
+	var sniffTags []string
+	for _, inbound := range model.Inbounds {
+		converted, ok := singBoxInbound(inbound)
+		if !ok {
+			continue
+		}
+		inbounds = append(inbounds, converted)
+		if inbound.Sniffing != nil && inbound.Sniffing.Enabled {
+			sniffTags = append(sniffTags, inbound.Tag)
+		}
+	}
+
+	outbounds := []map[string]interface{}{
+		outbound,
+		{"type": "direct", "tag": "direct"},
+	}
+
+	var rules []map[string]interface{}
+	if len(sniffTags) > 0 {
+		rules = append(rules, map[string]interface{}{
+			"inbound": sniffTags,
+			"action":  "sniff",
+		})
+	}
+	ruleSets := make(map[string]map[string]interface{})
+	var ruleSetTags []string
+	for _, rule := range model.Routing.Rules {
+		converted, sets, ok := singBoxRule(rule)
+		if !ok {
+			continue
+		}
+		rules = append(rules, converted)
+		for tag, set := range sets {
+			if _, exists := ruleSets[tag]; !exists {
+				ruleSetTags = append(ruleSetTags, tag)
+			}
+			ruleSets[tag] = set
+		}
+	}
+
+	route := map[string]interface{}{
+		"rules": rules,
+		"final": "proxy",
+	}
+	if len(ruleSetTags) > 0 {
+		sets := make([]map[string]interface{}, 0, len(ruleSetTags))
+		for _, tag := range ruleSetTags {
+			sets = append(sets, ruleSets[tag])
+		}
+		route["rule_set"] = sets
+	}
+
+	experimental := map[string]interface{}{}
+	// 缓存远程规则集，避免每次启动都重新下载
+	// 缓存文件同一时间只能被一个进程打开，没有规则集的配置（如测速用的临时内核）不启用
+	if len(ruleSetTags) > 0 {
+		experimental["cache_file"] = map[string]interface{}{
+			"enabled": true,
+			"path":    filepath.Join(c.workDir, "sing-box-cache.db"),
+		}
+	}
+	// 通过Clash API查看和关闭活动连接
+	if model.controller != nil {
+		experimental["clash_api"] = map[string]interface{}{
+			"external_controller": model.controller.addr,
+			"secret":              model.controller.secret,
+		}
+	}
+
+	singBoxConfig := map[string]interface{}{
+		"log": map[string]interface{}{
+			"level":     "warn",
+			"timestamp": false,
+		},
+		"inbounds":     inbounds,
+		"outbounds":    outbounds,
+		"route":        route,
+		"experimental": experimental,
+	}
+
+	data, err := json.MarshalIndent(singBoxConfig, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal sing-box config: %w", err)
+	}
+	return data, nil
+}
+
+// singBoxInbound 转换本地入站，不支持的入站返回false
+func singBoxInbound(inbound InboundConfig) (map[string]interface{}, bool) {
+	var inboundType string
+	switch {
+	case inbound.Tag == "mixed-in":
+		inboundType = "mixed"
+	case inbound.Protocol == "socks":
+		inboundType = "socks"
+	case inbound.Protocol == "http":
+		inboundType = "http"
+	default:
+		return nil, false
+	}
+
+	converted := map[string]interface{}{
+		"type":        inboundType,
+		"tag":         inbound.Tag,
+		"listen":      inbound.Listen,
+		"listen_port": inbound.Port,
+	}
+	if accounts, ok := inbound.Settings["accounts"].([]map[string]interface{}); ok {
+		users := make([]map[string]interface{}, 0, len(accounts))
+		for _, account := range accounts {
+			users = append(users, map[string]interface{}{
+				"username": account["user"],
+				"password": account["pass"],
+			})
+		}
+		converted["users"] = users
+	}
+	return converted, true
+}
+
+// singBoxRule 转换路由规则，同时返回规则引用的远程规则集
+// 包含sing-box无法表达的条件（如ext:外部文件）时跳过该条件，规则没有剩余条件时返回false
+func singBoxRule(rule RuleConfig) (map[string]interface{}, map[string]map[string]interface{}, bool) {
+	converted := make(map[string]interface{})
+	sets := make(map[string]map[string]interface{})
+	var ruleSetTags []string
+	hasTarget := false
+
+	addRuleSet := func(kind, name, urlFormat string) {
+		tag := kind + "-" + name
+		sets[tag] = map[string]interface{}{
+			"type":   "remote",
+			"tag":    tag,
+			"format": "binary",
+			"url":    fmt.Sprintf(urlFormat, name),
+		}
+		ruleSetTags = append(ruleSetTags, tag)
+	}
+	appendValue := func(key, value string) {
+		values, _ := converted[key].([]string)
+		converted[key] = append(values, value)
+	}
+
+	for _, domain := range rule.Domain {
+		switch {
+		case strings.HasPrefix(domain, "geosite:"):
+			addRuleSet("geosite", strings.TrimPrefix(domain, "geosite:"), singBoxGeositeURL)
+		case strings.HasPrefix(domain, "full:"):
+			appendValue("domain", strings.TrimPrefix(domain, "full:"))
+		case strings.HasPrefix(domain, "domain:"):
+			appendValue("domain_suffix", strings.TrimPrefix(domain, "domain:"))
+		case strings.HasPrefix(domain, "regexp:"):
+			appendValue("domain_regex", strings.TrimPrefix(domain, "regexp:"))
+		case strings.HasPrefix(domain, "keyword:"):
+			appendValue("domain_keyword", strings.TrimPrefix(domain, "keyword:"))
+		case strings.HasPrefix(domain, "ext:"):
+			fmt.Printf("Skipped unsupported sing-box domain condition: %s\n", domain)
+			continue
+		default:
+			// Xray中不带前缀的域名按子串匹配
+			appendValue("domain_keyword", domain)
+		}
+		hasTarget = true
+	}
+
+	for _, ip := range rule.IP {
+		switch {
+		case ip == "geoip:private":
+			converted["ip_is_private"] = true
+		case strings.HasPrefix(ip, "geoip:"):
+			addRuleSet("geoip", strings.TrimPrefix(ip, "geoip:"), singBoxGeoIPURL)
+		case strings.HasPrefix(ip, "ext:"):
+			fmt.Printf("Skipped unsupported sing-box ip condition: %s\n", ip)
+			continue
+		default:
+			appendValue("ip_cidr", singBoxCIDR(ip))
+		}
+		hasTarget = true
+	}
+
+	// 条件全部被跳过的规则不能保留，否则会匹配所有流量
+	if (len(rule.Domain) > 0 || len(rule.IP) > 0) && !hasTarget {
+		return nil, nil, false
+	}
+	if len(ruleSetTags) > 0 {
+		converted["rule_set"] = ruleSetTags
+	}
+
+	if len(rule.InboundTag) > 0 {
+		converted["inbound"] = rule.InboundTag
+	}
+	if len(rule.Process) > 0 {
+		key, values := singBoxProcess(rule.Process)
+		converted[key] = values
+	}
+	if rule.Network != "" {
+		converted["network"] = strings.Split(rule.Network, ",")
+	}
+	if rule.Port != "" {
+		for _, part := range strings.Split(rule.Port, ",") {
+			part = strings.TrimSpace(part)
+			if from, to, ok := strings.Cut(part, "-"); ok {
+				ranges, _ := converted["port_range"].([]string)
+				converted["port_range"] = append(ranges, from+":"+to)
+			} else if port, err := strconv.Atoi(part); err == nil {
+				ports, _ := converted["port"].([]int)
+				converted["port"] = append(ports, port)
+			}
+		}
+	}
+
+	switch rule.OutboundTag {
+	case "block":
+		converted["action"] = "reject"
+	case "dns-out", apiTag:
+		// FakeDNS和API规则只对Xray有意义
+		return nil, nil, false
+	default:
+		converted["action"] = "route"
+		converted["outbound"] = rule.OutboundTag
+	}
+	return converted, sets, true
+}
+
+// singBoxProcess 转换进程条件：带路径分隔符的值是完整路径，以分隔符结尾的是目录
+// sing-box的process_name和process_path之间是"与"的关系，同一规则混用进程名、路径或目录时统一转换为process_path_regex
+func singBoxProcess(values []string) (string, []string) {
+	var names, paths []string
+	hasDir := false
+	for _, value := range values {
+		if !strings.ContainsAny(value, `/\`) {
+			names = append(names, value)
+			continue
+		}
+		paths = append(paths, value)
+		if strings.HasSuffix(value, "/") || strings.HasSuffix(value, `\`) {
+			hasDir = true
+		}
+	}
+	switch {
+	case len(paths) == 0:
+		return "process_name", names
+	case len(names) == 0 && !hasDir:
+		return "process_path", paths
+	}
+
+	patterns := make([]string, 0, len(values))
+	for _, value := range values {
+		switch {
+		case !strings.ContainsAny(value, `/\`):
+			// 进程名匹配路径的最后一段
+			patterns = append(patterns, `(^|[/\\])`+regexp.QuoteMeta(value)+"$")
+		case strings.HasSuffix(value, "/") || strings.HasSuffix(value, `\`):
+			patterns = append(patterns, "^"+regexp.QuoteMeta(value))
+		default:
+			patterns = append(patterns, "^"+regexp.QuoteMeta(value)+"$")
+		}
+	}
+	return "process_path_regex", patterns
+}
+
+// singBoxCIDR 将单个IP转换为CIDR
+func singBoxCIDR(value string) string {
+	if strings.Contains(value, "/") {
+		return value
+	}
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return value
+	}
+	if ip.To4() != nil {
+		return value + "/32"
+	}
+	return value + "/128"
+}
+
+// singBoxOutbound 根据服务器配置生成proxy出站
+func singBoxOutbound(srv *server.ServerConfig) (map[string]interface{}, error) {
+	outbound := map[string]interface{}{
+		"type":        srv.Protocol,
+		"tag":         "proxy",
+		"server":      srv.Address,
+		"server_port": srv.Port,
+	}
+
+	switch srv.Protocol {
+	case "vmess":
+		outbound["uuid"] = srv.UUID
+		outbound["security"] = "auto"
+		outbound["alter_id"] = 0
+	case "vless":
+		outbound["uuid"] = srv.UUID
+	case "trojan":
+		outbound["password"] = srv.Password
+	case "shadowsocks":
+		outbound["method"] = srv.Method
+		outbound["password"] = srv.Password
+	case "hysteria2":
+		outbound["password"] = srv.Password
+		if srv.UpMbps > 0 {
+			outbound["up_mbps"] = srv.UpMbps
+		}
+		if srv.DownMbps > 0 {
+			outbound["down_mbps"] = srv.DownMbps
+		}
+		if srv.Obfs != "" {
+			outbound["obfs"] = map[string]interface{}{
+				"type":     srv.Obfs,
+				"password": srv.ObfsPassword,
+			}
+		}
+	case "tuic":
+		outbound["uuid"] = srv.UUID
+		outbound["password"] = srv.Password
+		if srv.CongestionControl != "" {
+			outbound["congestion_control"] = srv.CongestionControl
+		}
+		if srv.UDPRelayMode != "" {
+			outbound["udp_relay_mode"] = srv.UDPRelayMode
+		}
+	default:
+		return nil, fmt.Errorf("unsupported protocol %s", srv.Protocol)
+	}
+
+	// Hysteria2和TUIC必须使用TLS
+	quic := srv.Protocol == "hysteria2" || srv.Protocol == "tuic"
+	if srv.TLS || quic {
+		serverName := srv.SNI
+		if serverName == "" {
+			serverName = srv.Address
+		}
+		tls := map[string]interface{}{
+			"enabled":     true,
+			"server_name": serverName,
+		}
+		alpn := srv.ALPN
+		if len(alpn) == 0 && srv.Protocol == "tuic" {
+			alpn = []string{"h3"}
+		}
+		if len(alpn) > 0 {
+			tls["alpn"] = alpn
+		}
+		if srv.Insecure {
+			tls["insecure"] = true
+		}
+		outbound["tls"] = tls
+	}
+	if quic {
+		return outbound, nil
+	}
+
+	switch srv.Network {
+	case "", "tcp":
+	case "ws":
+		transport := map[string]interface{}{
+			"type": "ws",
+			"path": srv.Path,
+		}
+		if srv.Host != "" {
+			transport["headers"] = map[string]interface{}{"Host": srv.Host}
+		}
+		outbound["transport"] = transport
+	case "h2":
+		transport := map[string]interface{}{
+			"type": "http",
+			"path": srv.Path,
+		}
+		if srv.Host != "" {
+			transport["host"] = []string{srv.Host}
+		}
+		outbound["transport"] = transport
+	case "httpupgrade":
+		outbound["transport"] = map[string]interface{}{
+			"type": "httpupgrade",
+			"host": srv.Host,
+			"path": srv.Path,
+		}
+	case "grpc":
+		outbound["transport"] = map[string]interface{}{
+			"type":         "grpc",
+			"service_name": srv.Path,
+		}
+	default:
+		return nil, fmt.Errorf("unsupported network %s", srv.Network)
+	}
+	return outbound, nil
+}
@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestSingBoxProcessRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		process []string
+		key     string
+		values  []string
+	}{
+		{
+			name:    "names",
+			process: []string{"curl", "chrome.exe"},
+			key:     "process_name",
+			values:  []string{"curl", "chrome.exe"},
+		},
+		{
+			name:    "full paths",
+			process: []string{"/usr/bin/curl", `C:\Program Files\Google\Chrome\chrome.exe`},
+			key:     "process_path",
+			values:  []string{"/usr/bin/curl", `C:\Program Files\Google\Chrome\chrome.exe`},
+		},
+		{
+			name:    "names and paths",
+			process: []string{"curl", "/opt/app/bin/app"},
+			key:     "process_path_regex",
+			values:  []string{`(^|[/\\])curl$`, `^/opt/app/bin/app$`},
+		},
+		{
+			name:    "directory",
+			process: []string{"/opt/app/"},
+			key:     "process_path_regex",
+			values:  []string{`^/opt/app/`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converted, _, ok := singBoxRule(RuleConfig{Type: "field", OutboundTag: "direct", Process: tt.process})
+			if !ok {
+				t.Fatal("rule was dropped")
+			}
+			for _, key := range []string{"process_name", "process_path", "process_path_regex"} {
+				if _, exists := converted[key]; exists != (key == tt.key) {
+					t.Errorf("unexpected presence of %s in %v", key, converted)
+				}
+			}
+			if got := converted[tt.key]; !reflect.DeepEqual(got, tt.values) {
+				t.Errorf("%s = %v, want %v", tt.key, got, tt.values)
+			}
+		})
+	}
+}
+
+func TestSingBoxProcessRegexMatches(t *testing.T) {
+	_, patterns := singBoxProcess([]string{"curl.exe", `C:\Tools\`})
+	matches := func(path string) bool {
+		for _, pattern := range patterns {
+			if regexp.MustCompile(pattern).MatchString(path) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for path, want := range map[string]bool{
+		`C:\Windows\System32\curl.exe`: true,
+		`C:\Tools\wget.exe`:            true,
+		`C:\Windows\notcurl.exe`:       false,
+		`D:\Tools\wget.exe`:            false,
+	} {
+		if got := matches(path); got != want {
+			t.Errorf("match %s = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package proxy
+
+import (
+	"Gox/config"
+	"Gox/server"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// speedTestInboundTag 测速内核的入站标签
+	speedTestInboundTag = "speedtest-in"
+	// speedTestResponseTimeout 等待测速地址响应的超时时间
+	speedTestResponseTimeout = 15 * time.Second
+	// speedTestBufferSize 读取响应的缓冲区大小
+	speedTestBufferSize = 32 * 1024
+)
+
+// SpeedTestResult 带宽测速结果
+type SpeedTestResult struct {
+	ServerID   string    `json:"serverId"`
+	ServerName string    `json:"serverName"`
+	Core       string    `json:"core"`      // 测速使用的内核
+	URL        string    `json:"url"`       // 测速地址
+	Bytes      int64     `json:"bytes"`     // 下载的字节数
+	Duration   float64   `json:"duration"`  // 下载耗时(秒)，不含首字节时间
+	Mbps       float64   `json:"mbps"`      // 平均下载速度(Mbps)
+	TTFB       int64     `json:"ttfb"`      // 首字节时间(毫秒)
+	Error      string    `json:"error"`     // 测速失败的原因
+	Cancelled  bool      `json:"cancelled"` // 是否被取消
+	StartedAt  time.Time `json:"startedAt"` // 开始时间
+}
+
+// speedTestLimits 测速地址以及下载时长和字节数上限
+type speedTestLimits struct {
+	url      string
+	duration time.Duration
+	maxBytes int64
+}
+
+// newSpeedTestLimits 读取测速配置，未设置的项使用默认值
+func newSpeedTestLimits(cfg config.SpeedTestConfig) speedTestLimits {
+	limits := speedTestLimits{
+		url:      cfg.URL,
+		duration: time.Duration(cfg.Duration) * time.Second,
+		maxBytes: int64(cfg.MaxMB) * 1024 * 1024,
+	}
+	if limits.url == "" {
+		limits.url = config.DefaultSpeedTestURL
+	}
+	if limits.duration <= 0 {
+		limits.duration = config.DefaultSpeedTestDuration * time.Second
+	}
+	if limits.maxBytes <= 0 {
+		limits.maxBytes = config.DefaultSpeedTestMaxMB * 1024 * 1024
+	}
+	return limits
+}
+
+// SpeedTest 通过临时内核实例下载测速地址，测试服务器的带宽
+// 同一时间只运行一个测速，其余的排队等待，避免互相影响
+func (m *XrayProxyManager) SpeedTest(srv *server.ServerConfig) SpeedTestResult {
+	result := SpeedTestResult{ServerID: srv.ID, ServerName: srv.Name}
+	ctx := m.speedTestContext()
+
+	select {
+	case m.speedTestSlot <- struct{}{}:
+		defer func() { <-m.speedTestSlot }()
+	case <-ctx.Done():
+		result.cancel()
+		return result
+	}
+
+	limits := newSpeedTestLimits(m.proxyConfig().SpeedTest)
+	result.URL = limits.url
+	result.StartedAt = time.Now()
+
+	core, proxyAddr, stop, err := m.startSpeedTestCore(ctx, srv)
+	if core != nil {
+		result.Core = core.Name()
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			result.cancel()
+		} else {
+			result.Error = err.Error()
+		}
+		return result
+	}
+	defer stop()
+
+	client := &http.Client{Transport: m.speedTestTransport(proxyAddr)}
+	measureThroughput(ctx, client, limits, &result)
+	if ctx.Err() != nil {
+		result.cancel()
+	}
+	return result
+}
+
+// CancelSpeedTest 取消正在运行和排队等待的测速
+func (m *XrayProxyManager) CancelSpeedTest() {
+	m.speedTestMu.Lock()
+	defer m.speedTestMu.Unlock()
+
+	if m.speedTestCancel != nil {
+		m.speedTestCancel()
+		m.speedTestCtx = nil
+		m.speedTestCancel = nil
+	}
+}
+
+// speedTestContext 获取当前这批测速共用的上下文，取消后下一次测速重新创建
+func (m *XrayProxyManager) speedTestContext() context.Context {
+	m.speedTestMu.Lock()
+	defer m.speedTestMu.Unlock()
+
+	if m.speedTestCtx == nil {
+		m.speedTestCtx, m.speedTestCancel = context.WithCancel(context.Background())
+	}
+	return m.speedTestCtx
+}
+
+// startSpeedTestCore 启动只包含一个SOCKS入站和服务器出站的临时内核，返回入站地址和停止函数
+// 临时内核不使用FakeDNS、路由规则等全局设置，与正在运行的代理互不影响
+func (m *XrayProxyManager) startSpeedTestCore(ctx context.Context, srv *server.ServerConfig) (Core, string, func(), error) {
+	if isCustomServer(srv) {
+		return nil, "", nil, &CapabilityError{Feature: "speed test", Reason: "custom config servers cannot be started with a temporary inbound"}
+	}
+
+	proxyCfg := m.proxyConfig()
+	proxyCfg.FakeDNS.Enabled = false
+	core, err := m.selectCore(srv, proxyCfg)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	binary, err := core.Binary()
+	if err != nil {
+		return core, "", nil, err
+	}
+
+	port, err := freeLocalPort()
+	if err != nil {
+		return core, "", nil, err
+	}
+	model := &XrayConfig{
+		Log: LogConfig{LogLevel: "warning"},
+		Inbounds: []InboundConfig{{
+			Tag:      speedTestInboundTag,
+			Listen:   "127.0.0.1",
+			Port:     port,
+			Protocol: "socks",
+			Settings: map[string]interface{}{"auth": "noauth", "udp": false},
+		}},
+		Outbounds: []OutboundConfig{
+			m.generateOutboundConfig(srv),
+			{Tag: "direct", Protocol: "freedom"},
+		},
+		Routing: RoutingConfig{DomainStrategy: "AsIs"},
+	}
+	data, err := core.GenerateConfig(model, srv)
+	if err != nil {
+		return core, "", nil, err
+	}
+	configPath := filepath.Join(m.workDir, "speedtest_"+coreConfigFile(core.Name()))
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
+		return core, "", nil, fmt.Errorf("failed to write speed test config file: %w", err)
+	}
+
+	proc, err := startCoreProcess(core.Command(ctx, binary.Path, configPath), core)
+	if err != nil {
+		os.Remove(configPath)
+		return core, "", nil, err
+	}
+	stop := func() {
+		proc.stop(stopTimeout)
+		os.Remove(configPath)
+	}
+	if err := waitForReady(proc, model.Inbounds, startupTimeout); err != nil {
+		stop()
+		return core, "", nil, err
+	}
+	return core, net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), stop, nil
+}
+
+// measureThroughput 下载测速地址直到达到时长或字节数上限，记录首字节时间和平均速度
+func measureThroughput(ctx context.Context, client *http.Client, limits speedTestLimits, result *SpeedTestResult) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, limits.url, nil)
+	if err != nil {
+		result.Error = fmt.Sprintf("invalid speed test url: %v", err)
+		return
+	}
+
+	var responseTimeout atomic.Bool
+	responseTimer := time.AfterFunc(speedTestResponseTimeout, func() {
+		responseTimeout.Store(true)
+		cancel()
+	})
+	start := time.Now()
+	resp, err := client.Do(req)
+	responseTimer.Stop()
+	if err != nil {
+		if responseTimeout.Load() {
+			err = fmt.Errorf("no response within %s", speedTestResponseTimeout)
+		}
+		result.Error = fmt.Sprintf("failed to request speed test url: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	firstByte := time.Now()
+	result.TTFB = firstByte.Sub(start).Milliseconds()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		result.Error = fmt.Sprintf("unexpected status %s", resp.Status)
+		return
+	}
+
+	// 达到测速时长后中断下载，此时的读取错误属于正常结束
+	var finished atomic.Bool
+	timer := time.AfterFunc(limits.duration, func() {
+		finished.Store(true)
+		cancel()
+	})
+	defer timer.Stop()
+
+	buf := make([]byte, speedTestBufferSize)
+	for {
+		n, err := resp.Body.Read(buf)
+		result.Bytes += int64(n)
+		if result.Bytes >= limits.maxBytes || err == io.EOF {
+			break
+		}
+		if err != nil {
+			if !finished.Load() {
+				result.Error = fmt.Sprintf("failed to download speed test url: %v", err)
+			}
+			break
+		}
+	}
+
+	elapsed := time.Since(firstByte)
+	result.Duration = elapsed.Seconds()
+	if elapsed > 0 {
+		result.Mbps = float64(result.Bytes) * 8 / elapsed.Seconds() / 1e6
+	}
+}
+
+// cancel 标记测速被取消
+func (r *SpeedTestResult) cancel() {
+	r.Cancelled = true
+	r.Error = "speed test cancelled"
+}
+
+// socksTransport 通过测速内核的SOCKS入站访问测速地址，关闭压缩以统计实际传输的字节数
+func socksTransport(proxyAddr string) http.RoundTripper {
+	return &http.Transport{
+		Proxy:              http.ProxyURL(&url.URL{Scheme: "socks5", Host: proxyAddr}),
+		DisableKeepAlives:  true,
+		DisableCompression: true,
+	}
+}
+
+// freeLocalPort 获取一个空闲的本地端口
+func freeLocalPort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to find a free local port: %w", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
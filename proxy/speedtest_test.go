@@ -0,0 +1,205 @@
+package proxy
+
+import (
+	"Gox/config"
+	"Gox/server"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSpeedTestHelperProcess 充当测速用的临时内核，输出启动完成日志后一直运行到被结束
+func TestSpeedTestHelperProcess(t *testing.T) {
+	if os.Getenv("GOX_SPEEDTEST_HELPER") != "1" {
+		return
+	}
+	fmt.Println("fake core started")
+	time.Sleep(time.Minute)
+	os.Exit(0)
+}
+
+// fakeCore 启动测试进程自身作为内核，并记录最后一次启动的命令
+type fakeCore struct {
+	cmd *exec.Cmd
+}
+
+func (c *fakeCore) Name() string { return config.CoreXray }
+
+func (c *fakeCore) Binary() (*CoreInfo, error) {
+	return &CoreInfo{Path: os.Args[0], Version: "test"}, nil
+}
+
+func (c *fakeCore) Capabilities() CoreCapabilities {
+	return CoreCapabilities{Core: config.CoreXray, Available: true, Protocols: []string{"vless"}, Transports: []string{"tcp"}}
+}
+
+func (c *fakeCore) GenerateConfig(model *XrayConfig, srv *server.ServerConfig) ([]byte, error) {
+	return json.Marshal(model)
+}
+
+func (c *fakeCore) Command(ctx context.Context, binary, configPath string) *exec.Cmd {
+	c.cmd = exec.CommandContext(ctx, binary, "-test.run=^TestSpeedTestHelperProcess$")
+	c.cmd.Env = append(os.Environ(), "GOX_SPEEDTEST_HELPER=1")
+	return c.cmd
+}
+
+func (c *fakeCore) TestConfig(binary, configPath string) error { return nil }
+
+func (c *fakeCore) IsReadyLine(line string) bool { return strings.HasSuffix(line, "started") }
+
+// newSpeedTestManager 创建使用fakeCore的管理器，测速请求被转发到target，proxyAddrs记录传给传输层的入站地址
+func newSpeedTestManager(t *testing.T, target string) (*XrayProxyManager, *fakeCore, chan string) {
+	t.Helper()
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	core := &fakeCore{}
+	proxyAddrs := make(chan string, 1)
+	m := &XrayProxyManager{
+		state:         newStatusMachine(),
+		workDir:       t.TempDir(),
+		cores:         map[string]Core{config.CoreXray: core},
+		coreOrder:     []string{config.CoreXray},
+		accessLog:     NewAccessLogBuffer(config.DefaultAccessLogRecords),
+		speedTestSlot: make(chan struct{}, 1),
+	}
+	m.speedTestTransport = func(proxyAddr string) http.RoundTripper {
+		proxyAddrs <- proxyAddr
+		return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			req.URL.Scheme = targetURL.Scheme
+			req.URL.Host = targetURL.Host
+			return (&http.Transport{DisableKeepAlives: true, DisableCompression: true}).RoundTrip(req)
+		})
+	}
+	return m, core, proxyAddrs
+}
+
+// roundTripFunc 把函数适配为http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// assertCoreStopped 检查临时内核进程已退出且配置文件已删除
+func assertCoreStopped(t *testing.T, m *XrayProxyManager, core *fakeCore) {
+	t.Helper()
+	if core.cmd == nil {
+		t.Fatal("temporary core was not started")
+	}
+	if core.cmd.ProcessState == nil {
+		t.Error("temporary core is still running")
+	}
+	configPath := filepath.Join(m.workDir, "speedtest_"+coreConfigFile(config.CoreXray))
+	if _, err := os.Stat(configPath); !os.IsNotExist(err) {
+		t.Errorf("speed test config was not removed: %v", err)
+	}
+}
+
+func testServer() *server.ServerConfig {
+	return &server.ServerConfig{ID: "s1", Name: "Test", Protocol: "vless", Address: "example.com", Port: 443, UUID: "id"}
+}
+
+func TestSpeedTestMeasuresThroughput(t *testing.T) {
+	const size = 256 * 1024
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, size))
+	}))
+	defer target.Close()
+
+	m, core, proxyAddrs := newSpeedTestManager(t, target.URL)
+	result := m.SpeedTest(testServer())
+	if result.Error != "" {
+		t.Fatalf("speed test failed: %s", result.Error)
+	}
+	if result.Bytes != size {
+		t.Errorf("bytes = %d, want %d", result.Bytes, size)
+	}
+	if result.Mbps <= 0 || result.Duration <= 0 {
+		t.Errorf("speed = %v Mbps over %v s, want positive values", result.Mbps, result.Duration)
+	}
+	if result.Core != config.CoreXray || result.ServerID != "s1" {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	// 请求经过临时内核的本地入站
+	addr := <-proxyAddrs
+	if host, _, err := net.SplitHostPort(addr); err != nil || host != "127.0.0.1" {
+		t.Errorf("proxy address = %q, want a local inbound", addr)
+	}
+	assertCoreStopped(t, m, core)
+}
+
+func TestMeasureThroughputMaxBytes(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chunk := make([]byte, speedTestBufferSize)
+		for r.Context().Err() == nil {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+		}
+	}))
+	defer target.Close()
+
+	var result SpeedTestResult
+	limits := speedTestLimits{url: target.URL, duration: 10 * time.Second, maxBytes: 1024 * 1024}
+	measureThroughput(context.Background(), target.Client(), limits, &result)
+	if result.Error != "" {
+		t.Fatalf("measure failed: %s", result.Error)
+	}
+	if result.Bytes < limits.maxBytes || result.Bytes >= limits.maxBytes+speedTestBufferSize {
+		t.Errorf("bytes = %d, want the download to stop at %d", result.Bytes, limits.maxBytes)
+	}
+}
+
+func TestSpeedTestCancel(t *testing.T) {
+	streaming := make(chan struct{})
+	stopped := make(chan struct{})
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, speedTestBufferSize))
+		w.(http.Flusher).Flush()
+		close(streaming)
+		<-r.Context().Done()
+		close(stopped)
+	}))
+	defer target.Close()
+
+	m, core, _ := newSpeedTestManager(t, target.URL)
+	results := make(chan SpeedTestResult, 1)
+	go func() { results <- m.SpeedTest(testServer()) }()
+
+	select {
+	case <-streaming:
+	case <-time.After(10 * time.Second):
+		t.Fatal("download did not start")
+	}
+	m.CancelSpeedTest()
+
+	var result SpeedTestResult
+	select {
+	case result = <-results:
+	case <-time.After(5 * time.Second):
+		t.Fatal("speed test did not return after cancel")
+	}
+	if !result.Cancelled {
+		t.Errorf("result is not marked cancelled: %+v", result)
+	}
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Error("transfer was not stopped")
+	}
+	assertCoreStopped(t, m, core)
+}
@@ -0,0 +1,227 @@
+package proxy
+
+import (
+	"Gox/config"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// statusTransitions 允许的状态迁移，同一状态之间不算迁移
+var statusTransitions = map[ProxyStatus][]ProxyStatus{
+	StatusStopped:    {StatusConnecting},
+	StatusConnecting: {StatusRunning, StatusError, StatusStopped},
+	StatusRunning:    {StatusStopped, StatusError, StatusConnecting},
+	StatusError:      {StatusConnecting, StatusStopped},
+}
+
+// StatusInfo 代理状态详情
+type StatusInfo struct {
+	Status      ProxyStatus     `json:"status"`      // 当前状态
+	ServerID    string          `json:"serverId"`    // 当前服务器ID
+	Error       string          `json:"error"`       // 当前状态的错误信息，仅error状态有值
+	LastError   string          `json:"lastError"`   // 最近一次错误信息
+	Since       time.Time       `json:"since"`       // 进入当前状态的时间
+	StartedAt   time.Time       `json:"startedAt"`   // 开始运行的时间，未运行时为零值
+	Uptime      int64           `json:"uptime"`      // 已运行秒数
+	Core        string          `json:"core"`        // 运行中的内核，未运行时为当前Xray
+	CoreVersion string          `json:"coreVersion"` // 内核版本
+	Inbounds    []InboundStatus `json:"inbounds"`    // 运行中的本地入站
+}
+
+// InboundStatus 运行中的入站，完整配置的服务器从配置中识别
+type InboundStatus struct {
+	Tag      string `json:"tag"`      // 入站标签
+	Protocol string `json:"protocol"` // 入站协议
+	Listen   string `json:"listen"`   // 监听地址
+	Port     int    `json:"port"`     // 监听端口
+}
+
+// StatusEvent 状态变化事件
+type StatusEvent struct {
+	Status    ProxyStatus `json:"status"`    // 新状态
+	Previous  ProxyStatus `json:"previous"`  // 原状态
+	ServerID  string      `json:"serverId"`  // 当前服务器ID
+	Error     string      `json:"error"`     // 错误信息
+	Timestamp time.Time   `json:"timestamp"` // 变化时间
+}
+
+// statusMachine 代理状态机，所有状态变化都经过它校验并按顺序通知监听者
+type statusMachine struct {
+	status    ProxyStatus
+	err       string
+	lastError string
+	since     time.Time
+	startedAt time.Time
+
+	listenerMu sync.RWMutex
+	listener   func(StatusEvent)
+	events     chan StatusEvent
+}
+
+// newStatusMachine 创建状态机，初始状态为已停止
+func newStatusMachine() *statusMachine {
+	s := &statusMachine{
+		status: StatusStopped,
+		since:  time.Now(),
+		events: make(chan StatusEvent, 64),
+	}
+	go s.dispatch()
+	return s
+}
+
+// canTransition 检查状态迁移是否合法
+func canTransition(from, to ProxyStatus) bool {
+	for _, next := range statusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// transition 切换状态，非法迁移会被拒绝（调用方需持有管理器的锁）
+func (s *statusMachine) transition(to ProxyStatus, serverID string, err error) bool {
+	if to == s.status {
+		return true
+	}
+	if !canTransition(s.status, to) {
+		fmt.Printf("Refused illegal proxy status transition from %s to %s\n", s.status, to)
+		return false
+	}
+
+	now := time.Now()
+	event := StatusEvent{
+		Status:    to,
+		Previous:  s.status,
+		ServerID:  serverID,
+		Timestamp: now,
+	}
+
+	s.status = to
+	s.since = now
+	s.err = ""
+	if err != nil {
+		s.err = err.Error()
+		s.lastError = s.err
+		event.Error = s.err
+	}
+	if to == StatusRunning {
+		s.startedAt = now
+	} else {
+		s.startedAt = time.Time{}
+	}
+
+	// 事件通过单独的goroutine按顺序发送，避免在持有锁时回调
+	s.send(event)
+	return true
+}
+
+// announce 状态不变但当前服务器发生变化时通知监听者（调用方需持有管理器的锁）
+func (s *statusMachine) announce(serverID string) {
+	s.send(StatusEvent{
+		Status:    s.status,
+		Previous:  s.status,
+		ServerID:  serverID,
+		Timestamp: time.Now(),
+	})
+}
+
+// send 将事件放入发送队列，队列已满时丢弃
+func (s *statusMachine) send(event StatusEvent) {
+	select {
+	case s.events <- event:
+	default:
+		fmt.Printf("Dropped proxy status event: %s -> %s\n", event.Previous, event.Status)
+	}
+}
+
+// info 获取状态详情（调用方需持有管理器的锁）
+func (s *statusMachine) info(serverID string) StatusInfo {
+	info := StatusInfo{
+		Status:    s.status,
+		ServerID:  serverID,
+		Error:     s.err,
+		LastError: s.lastError,
+		Since:     s.since,
+		StartedAt: s.startedAt,
+	}
+	if !s.startedAt.IsZero() {
+		info.Uptime = int64(time.Since(s.startedAt).Seconds())
+	}
+	return info
+}
+
+// setListener 设置状态变化监听者
+func (s *statusMachine) setListener(listener func(StatusEvent)) {
+	s.listenerMu.Lock()
+	defer s.listenerMu.Unlock()
+	s.listener = listener
+}
+
+// dispatch 将状态事件依次发送给监听者
+func (s *statusMachine) dispatch() {
+	for event := range s.events {
+		s.listenerMu.RLock()
+		listener := s.listener
+		s.listenerMu.RUnlock()
+		if listener != nil {
+			listener(event)
+		}
+	}
+}
+
+// OnStatusChange 设置状态变化回调，回调在单独的goroutine中按顺序执行
+func (m *XrayProxyManager) OnStatusChange(listener func(StatusEvent)) {
+	m.state.setListener(listener)
+}
+
+// GetStatusInfo 获取代理状态详情
+func (m *XrayProxyManager) GetStatusInfo() StatusInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	info := m.state.info(m.activeServerID())
+	if m.proc != nil && m.activeCore != nil {
+		info.Core = m.activeCore.Name()
+		info.CoreVersion = m.runningVersion
+		info.Inbounds = runningInbounds(m.runningConfig)
+	} else {
+		info.Core = config.CoreXray
+		info.CoreVersion = m.GetCoreInfo().Version
+	}
+	return info
+}
+
+// runningInbounds 运行配置中的本地入站，不包括内部使用的API入站
+func runningInbounds(xrayConfig *XrayConfig) []InboundStatus {
+	if xrayConfig == nil {
+		return nil
+	}
+	var inbounds []InboundStatus
+	for _, inbound := range xrayConfig.Inbounds {
+		if inbound.Tag == apiInboundTag {
+			continue
+		}
+		inbounds = append(inbounds, InboundStatus{
+			Tag:      inbound.Tag,
+			Protocol: inbound.Protocol,
+			Listen:   inbound.Listen,
+			Port:     inbound.Port,
+		})
+	}
+	return inbounds
+}
+
+// setStatusLocked 通过状态机切换状态（调用方需持有锁）
+func (m *XrayProxyManager) setStatusLocked(status ProxyStatus, err error) bool {
+	return m.state.transition(status, m.activeServerID(), err)
+}
+
+// activeServerID 当前服务器ID（调用方需持有锁）
+func (m *XrayProxyManager) activeServerID() string {
+	if m.activeServer == nil {
+		return ""
+	}
+	return m.activeServer.ID
+}
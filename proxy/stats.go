@@ -0,0 +1,196 @@
+package proxy
+
+import (
+	"Gox/server"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// statsInterval 查询流量统计的间隔，每次查询都要启动一个xray api进程
+	statsInterval = 2 * time.Second
+	// proxyTrafficPattern proxy出站流量计数器的名称前缀
+	proxyTrafficPattern = "outbound>>>proxy>>>traffic>>>"
+)
+
+// TrafficStats 代理流量统计
+type TrafficStats struct {
+	Upload        int64     `json:"upload"`        // 上传速度 (字节/秒)
+	Download      int64     `json:"download"`      // 下载速度 (字节/秒)
+	TotalUpload   int64     `json:"totalUpload"`   // 本次运行累计上传字节数
+	TotalDownload int64     `json:"totalDownload"` // 本次运行累计下载字节数
+	Timestamp     time.Time `json:"timestamp"`     // 采样时间
+
+	// Server 查询时正在使用的服务器，这次采样的流量属于该服务器
+	Server *server.ServerConfig `json:"-"`
+}
+
+// statsQueryResponse `xray api statsquery` 的输出，int64按protobuf JSON规则可能编码为字符串
+type statsQueryResponse struct {
+	Stat []struct {
+		Name  string      `json:"name"`
+		Value json.Number `json:"value"`
+	} `json:"stat"`
+}
+
+// trafficMeter 根据累计计数器计算速度
+type trafficMeter struct {
+	last     time.Time
+	uplink   int64
+	downlink int64
+	stats    TrafficStats
+}
+
+// update 加入一次采样，返回新的统计结果
+func (t *trafficMeter) update(uplink, downlink int64, now time.Time) TrafficStats {
+	upDelta := counterDelta(t.uplink, uplink)
+	downDelta := counterDelta(t.downlink, downlink)
+
+	elapsed := now.Sub(t.last).Seconds()
+	if elapsed <= 0 {
+		elapsed = statsInterval.Seconds()
+	}
+
+	t.stats = TrafficStats{
+		Upload:        int64(float64(upDelta) / elapsed),
+		Download:      int64(float64(downDelta) / elapsed),
+		TotalUpload:   t.stats.TotalUpload + upDelta,
+		TotalDownload: t.stats.TotalDownload + downDelta,
+		Timestamp:     now,
+	}
+	t.last = now
+	t.uplink = uplink
+	t.downlink = downlink
+	return t.stats
+}
+
+// counterDelta 计数器的增量，计数器变小说明proxy出站被重建，从0重新计数
+func counterDelta(previous, current int64) int64 {
+	if current < previous {
+		return current
+	}
+	return current - previous
+}
+
+// queryProxyTraffic 通过StatsService查询proxy出站的累计上传和下载字节数
+func queryProxyTraffic(binary, addr string) (int64, int64, error) {
+	output, err := xrayAPI(binary, "statsquery", "--server="+addr, "-pattern", proxyTrafficPattern)
+	if err != nil {
+		return 0, 0, err
+	}
+	return parseTrafficStats(output)
+}
+
+// parseTrafficStats 解析statsquery输出中的上传和下载计数器，未产生流量的计数器不会出现在输出中
+func parseTrafficStats(output []byte) (int64, int64, error) {
+	var response statsQueryResponse
+	if err := json.Unmarshal(output, &response); err != nil {
+		return 0, 0, fmt.Errorf("failed to decode xray stats: %w", err)
+	}
+
+	var uplink, downlink int64
+	for _, stat := range response.Stat {
+		value, _ := stat.Value.Int64()
+		switch strings.TrimPrefix(stat.Name, proxyTrafficPattern) {
+		case "uplink":
+			uplink = value
+		case "downlink":
+			downlink = value
+		}
+	}
+	return uplink, downlink, nil
+}
+
+// OnTrafficStats 设置流量统计回调，代理运行期间每隔statsInterval调用一次，停止时推送一次零值
+func (m *XrayProxyManager) OnTrafficStats(listener func(TrafficStats)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.trafficListener = listener
+}
+
+// GetTrafficStats 获取最近一次流量统计
+func (m *XrayProxyManager) GetTrafficStats() TrafficStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.traffic
+}
+
+// pollTraffic 内核运行期间定时查询proxy出站的流量计数器并计算速度，进程退出后结束
+func (m *XrayProxyManager) pollTraffic(proc *coreProcess) {
+	ticker := time.NewTicker(statsInterval)
+	defer ticker.Stop()
+
+	m.statsMu.Lock()
+	m.meter = trafficMeter{last: time.Now()}
+	m.meterProc = proc
+	m.statsMu.Unlock()
+
+	reported := false
+	for {
+		select {
+		case <-proc.done:
+			m.publishTraffic(TrafficStats{Timestamp: time.Now()})
+			return
+		case <-ticker.C:
+		}
+
+		m.statsMu.Lock()
+		err := m.sampleTrafficLocked(proc)
+		m.statsMu.Unlock()
+		// 只提示一次，避免反复刷屏
+		if err != nil && !reported {
+			fmt.Printf("Failed to query traffic stats: %v\n", err)
+			reported = true
+		}
+	}
+}
+
+// sampleTrafficLocked 查询一次proxy出站的流量计数器并推送统计（调用方需持有statsMu）
+// 进程未运行或不支持统计时不做任何事
+func (m *XrayProxyManager) sampleTrafficLocked(proc *coreProcess) error {
+	if m.meterProc != proc {
+		return nil
+	}
+	binary, addr, srv, ok := m.statsTarget(proc)
+	if !ok {
+		return nil
+	}
+	uplink, downlink, err := queryProxyTraffic(binary, addr)
+	if err != nil {
+		return err
+	}
+	stats := m.meter.update(uplink, downlink, time.Now())
+	stats.Server = srv
+	m.publishTraffic(stats)
+	return nil
+}
+
+// statsTarget 获取查询流量统计所需的内核路径、API地址和当前服务器，进程未运行或不支持统计时返回false
+func (m *XrayProxyManager) statsTarget(proc *coreProcess) (string, string, *server.ServerConfig, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.proc != proc || m.state.status != StatusRunning || m.activeCore == nil {
+		return "", "", nil, false
+	}
+	// 完整配置的服务器不一定开启了统计
+	if !m.activeCore.Capabilities().hasFeature(FeatureStats) || isCustomServer(m.activeServer) {
+		return "", "", nil, false
+	}
+	addr := apiAddress(m.runningConfig)
+	return m.corePath, addr, m.activeServer, addr != ""
+}
+
+// publishTraffic 保存流量统计并通知监听者
+func (m *XrayProxyManager) publishTraffic(stats TrafficStats) {
+	m.mu.Lock()
+	m.traffic = stats
+	listener := m.trafficListener
+	m.mu.Unlock()
+
+	if listener != nil {
+		listener(stats)
+	}
+}
@@ -0,0 +1,196 @@
+package proxy
+
+import (
+	"Gox/config"
+	"Gox/server"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// maxCrashHistory 保留的崩溃记录数
+	maxCrashHistory = 20
+	// restartBaseDelay 首次重启前的等待时间
+	restartBaseDelay = time.Second
+	// restartMaxDelay 重启等待时间上限
+	restartMaxDelay = 30 * time.Second
+)
+
+// 崩溃后的处理方式
+const (
+	CrashActionRestart       = "restart"        // 已安排重启
+	CrashActionDisabled      = "disabled"       // 未启用自动重启
+	CrashActionGaveUp        = "gave_up"        // 超过重启次数上限
+	CrashActionInvalidConfig = "invalid_config" // 配置无效，重启无意义
+)
+
+// errRestartCancelled 自动重启已被用户操作取消
+var errRestartCancelled = errors.New("automatic restart was cancelled")
+
+// CrashRecord 内核崩溃记录
+type CrashRecord struct {
+	Time         time.Time `json:"time"`         // 崩溃时间
+	Server       string    `json:"server"`       // 当时连接的服务器
+	ExitCode     int       `json:"exitCode"`     // 退出码，未知时为-1
+	Reason       string    `json:"reason"`       // 崩溃或重启失败原因
+	Stderr       string    `json:"stderr"`       // stderr末尾内容
+	Attempt      int       `json:"attempt"`      // 时间窗口内第几次崩溃
+	Action       string    `json:"action"`       // 处理方式
+	RetryDelayMs int64     `json:"retryDelayMs"` // 距下次重启的等待时间(毫秒)
+}
+
+// GetCrashHistory 获取崩溃记录，最新的记录在前
+func (m *XrayProxyManager) GetCrashHistory() []CrashRecord {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history := make([]CrashRecord, 0, len(m.crashes))
+	for i := len(m.crashes) - 1; i >= 0; i-- {
+		history = append(history, m.crashes[i])
+	}
+	return history
+}
+
+// handleCrashLocked 记录崩溃并按策略安排重启（调用方需持有锁）
+// 检查配置需要运行内核，在锁外进行，检查通过后才真正安排重启
+func (m *XrayProxyManager) handleCrashLocked(srv *server.ServerConfig, record CrashRecord) {
+	policy := m.proxyConfig().AutoRestart
+	window := time.Duration(policy.WindowSeconds) * time.Second
+	if window <= 0 {
+		window = config.DefaultRestartWindowSeconds * time.Second
+	}
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = config.DefaultRestartAttempts
+	}
+
+	// 只统计时间窗口内的崩溃
+	recent := m.recentCrashes[:0]
+	for _, t := range m.recentCrashes {
+		if record.Time.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	m.recentCrashes = append(recent, record.Time)
+	record.Attempt = len(m.recentCrashes)
+
+	switch {
+	case !policy.Enabled || srv == nil:
+		record.Action = CrashActionDisabled
+	case record.Attempt > maxAttempts:
+		record.Action = CrashActionGaveUp
+	default:
+		delay := restartBackoff(record.Attempt)
+		record.Action = CrashActionRestart
+		record.RetryDelayMs = delay.Milliseconds()
+		go m.verifyAndScheduleRestart(srv, m.restartGen, m.activeCore, m.corePath, m.configPath, delay)
+	}
+
+	fmt.Printf("Core crashed (attempt %d, exit code %d): %s, action: %s\n",
+		record.Attempt, record.ExitCode, record.Reason, record.Action)
+
+	m.crashes = append(m.crashes, record)
+	if len(m.crashes) > maxCrashHistory {
+		m.crashes = m.crashes[len(m.crashes)-maxCrashHistory:]
+	}
+
+	if record.Action == CrashActionRestart {
+		// 保留当前服务器，等待重启
+		m.activeServer = srv
+		m.setStatusLocked(StatusConnecting, nil)
+		return
+	}
+	m.setStatusLocked(StatusError, errors.New(record.Reason))
+	m.activeServer = nil
+}
+
+// verifyAndScheduleRestart 检查崩溃时使用的配置，有效时安排重启，无效时放弃重启并更新崩溃记录
+func (m *XrayProxyManager) verifyAndScheduleRestart(srv *server.ServerConfig, gen int, core Core, binary, configPath string, delay time.Duration) {
+	var testErr error
+	if core != nil {
+		testErr = core.TestConfig(binary, configPath)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// 检查期间用户已经启动或停止了代理
+	if gen != m.restartGen || m.proc != nil {
+		return
+	}
+	if testErr == nil {
+		m.scheduleRestartLocked(srv, delay)
+		return
+	}
+
+	// 检查期间没有新的崩溃，最后一条记录就是本次崩溃
+	if n := len(m.crashes); n > 0 {
+		m.crashes[n-1].Action = CrashActionInvalidConfig
+		m.crashes[n-1].Reason = testErr.Error()
+		m.crashes[n-1].RetryDelayMs = 0
+	}
+	fmt.Printf("Core config is invalid, automatic restart cancelled: %v\n", testErr)
+	m.setStatusLocked(StatusError, testErr)
+	m.activeServer = nil
+}
+
+// scheduleRestartLocked 延迟后用同一服务器重启（调用方需持有锁）
+func (m *XrayProxyManager) scheduleRestartLocked(srv *server.ServerConfig, delay time.Duration) {
+	gen := m.restartGen
+	m.restartTimer = time.AfterFunc(delay, func() {
+		m.restart(srv, gen)
+	})
+}
+
+// cancelRestartLocked 取消待执行的自动重启并清空崩溃计数，用户主动启动或停止时调用（调用方需持有锁）
+func (m *XrayProxyManager) cancelRestartLocked() {
+	if m.restartTimer != nil {
+		m.restartTimer.Stop()
+		m.restartTimer = nil
+	}
+	m.restartGen++
+	m.recentCrashes = nil
+}
+
+// restart 执行一次自动重启，失败时按策略继续重试
+func (m *XrayProxyManager) restart(srv *server.ServerConfig, gen int) {
+	err := m.startProxy(context.Background(), srv, gen)
+	if err == nil || errors.Is(err, errRestartCancelled) {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// 重启期间用户已经启动或停止了代理
+	if gen != m.restartGen || m.proc != nil {
+		return
+	}
+
+	record := CrashRecord{
+		Time:     time.Now(),
+		Server:   srv.Name,
+		ExitCode: -1,
+		Reason:   err.Error(),
+	}
+	var startupErr *StartupError
+	if errors.As(err, &startupErr) {
+		record.ExitCode = startupErr.ExitCode
+		record.Stderr = startupErr.Stderr
+	}
+	m.handleCrashLocked(srv, record)
+}
+
+// restartBackoff 第attempt次崩溃后的等待时间，按指数增长
+func restartBackoff(attempt int) time.Duration {
+	delay := restartBaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= restartMaxDelay {
+			return restartMaxDelay
+		}
+	}
+	return delay
+}
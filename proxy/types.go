@@ -1,6 +1,7 @@
 package proxy
 
 import (
+	"Gox/config"
 	"Gox/server"
 	"context"
 )
@@ -27,28 +28,112 @@ type ProxyManager interface {
 	Stop() error
 	// GetStatus 获取代理状态
 	GetStatus() ProxyStatus
+	// GetStatusInfo 获取代理状态详情
+	GetStatusInfo() StatusInfo
+	// OnStatusChange 设置状态变化回调
+	OnStatusChange(listener func(StatusEvent))
+	// OnTrafficStats 设置流量统计回调
+	OnTrafficStats(listener func(TrafficStats))
+	// GetTrafficStats 获取最近一次流量统计
+	GetTrafficStats() TrafficStats
 	// GetActiveServer 获取当前活动的服务器配置
 	GetActiveServer() *server.ServerConfig
 	// IsRunning 检查代理是否正在运行
 	IsRunning() bool
+	// TestRoute 测试目标地址会命中的路由规则
+	TestRoute(target string) (*RouteTestResult, error)
+	// RefreshBlockLists 重新下载远程拦截列表
+	RefreshBlockLists() ([]BlockListStatus, error)
+	// GetBlockListStatus 获取拦截列表状态
+	GetBlockListStatus() []BlockListStatus
+	// ValidateRoutingRule 校验自定义路由规则
+	ValidateRoutingRule(rule config.RoutingRuleConfig) error
+	// ValidateXrayConfig 检查代理配置中的自定义Xray配置片段
+	ValidateXrayConfig(proxyCfg config.ProxyConfig) error
+	// RefreshPAC 重新生成PAC脚本
+	RefreshPAC() error
+	// GetPACURL 获取PAC地址
+	GetPACURL() string
+	// GetLANInfo 获取局域网共享信息
+	GetLANInfo() (*LANInfo, error)
+	// RegenerateLANCredentials 重新生成局域网认证信息
+	RegenerateLANCredentials() (*LANCredentials, error)
+	// QueryAccessLog 查询连接记录
+	QueryAccessLog(query AccessLogQuery) []AccessRecord
+	// GetCoreInfo 获取Xray内核信息
+	GetCoreInfo() CoreInfo
+	// ListCoreVersions 列出已安装的Xray版本
+	ListCoreVersions() ([]CoreVersion, error)
+	// InstallCoreVersion 安装Xray发布包
+	InstallCoreVersion(source, checksum string) (*CoreVersion, error)
+	// ActivateCoreVersion 切换Xray版本
+	ActivateCoreVersion(version string) (CoreInfo, error)
+	// RollbackCoreVersion 切换回上一个Xray版本
+	RollbackCoreVersion() (CoreInfo, error)
+	// GetCoreCapabilities 获取所有内核的能力
+	GetCoreCapabilities() []CoreCapabilities
+	// GetCrashHistory 获取内核崩溃记录
+	GetCrashHistory() []CrashRecord
+	// ListConnections 列出经过代理的活动连接
+	ListConnections() ([]Connection, error)
+	// CloseConnection 关闭指定连接
+	CloseConnection(id string) error
+	// CloseConnectionsToHost 关闭所有发往指定主机的连接
+	CloseConnectionsToHost(host string) (int, error)
+	// SpeedTest 测试服务器的下载带宽
+	SpeedTest(srv *server.ServerConfig) SpeedTestResult
+	// CancelSpeedTest 取消正在运行和排队等待的测速
+	CancelSpeedTest()
 }
 
 // XrayConfig Xray配置结构体
 type XrayConfig struct {
-	Log       LogConfig       `json:"log"`
-	Inbounds  []InboundConfig `json:"inbounds"`
-	Outbounds []OutboundConfig `json:"outbounds"`
-	Routing   RoutingConfig   `json:"routing"`
+	Log       LogConfig           `json:"log"`
+	API       *APIConfig          `json:"api,omitempty"`
+	Stats     *StatsConfig        `json:"stats,omitempty"`
+	Policy    *PolicyConfig       `json:"policy,omitempty"`
+	DNS       *DNSConfig          `json:"dns,omitempty"`
+	FakeDNS   []FakeDNSPoolConfig `json:"fakedns,omitempty"`
+	Inbounds  []InboundConfig     `json:"inbounds"`
+	Outbounds []OutboundConfig    `json:"outbounds"`
+	Routing   RoutingConfig       `json:"routing"`
+
+	// raw 合并自定义配置片段后的完整配置，包含结构体未定义的字段
+	raw []byte
+	// controller 连接管理API，仅支持连接管理的内核使用
+	controller *controllerConfig
+}
+
+// APIConfig API配置
+type APIConfig struct {
+	Tag      string   `json:"tag"`
+	Services []string `json:"services"`
+}
+
+// StatsConfig 流量统计配置，Xray只需要该字段存在
+type StatsConfig struct{}
+
+// PolicyConfig 策略配置
+type PolicyConfig struct {
+	System SystemPolicy `json:"system"`
+}
+
+// SystemPolicy 系统策略，控制是否统计出站流量
+type SystemPolicy struct {
+	StatsOutboundUplink   bool `json:"statsOutboundUplink"`
+	StatsOutboundDownlink bool `json:"statsOutboundDownlink"`
 }
 
 // LogConfig 日志配置
 type LogConfig struct {
 	LogLevel string `json:"loglevel"`
+	Access   string `json:"access,omitempty"`
 }
 
 // InboundConfig 入站配置
 type InboundConfig struct {
 	Tag      string                 `json:"tag"`
+	Listen   string                 `json:"listen,omitempty"`
 	Port     int                    `json:"port"`
 	Protocol string                 `json:"protocol"`
 	Settings map[string]interface{} `json:"settings,omitempty"`
@@ -57,37 +142,56 @@ type InboundConfig struct {
 
 // OutboundConfig 出站配置
 type OutboundConfig struct {
-	Tag           string                 `json:"tag"`
-	Protocol      string                 `json:"protocol"`
-	Settings      map[string]interface{} `json:"settings,omitempty"`
-	StreamSettings *StreamSettings       `json:"streamSettings,omitempty"`
+	Tag            string                 `json:"tag"`
+	Protocol       string                 `json:"protocol"`
+	Settings       map[string]interface{} `json:"settings,omitempty"`
+	StreamSettings *StreamSettings        `json:"streamSettings,omitempty"`
 }
 
 // SniffingConfig 流量探测配置
 type SniffingConfig struct {
 	Enabled      bool     `json:"enabled"`
 	DestOverride []string `json:"destOverride"`
+	RouteOnly    bool     `json:"routeOnly,omitempty"`
 }
 
 // StreamSettings 传输配置
 type StreamSettings struct {
-	Network    string                 `json:"network"`
-	Security   string                 `json:"security,omitempty"`
-	TLSSettings map[string]interface{} `json:"tlsSettings,omitempty"`
-	WSSettings  map[string]interface{} `json:"wsSettings,omitempty"`
-	TCPSettings map[string]interface{} `json:"tcpSettings,omitempty"`
+	Network             string                 `json:"network"`
+	Security            string                 `json:"security,omitempty"`
+	TLSSettings         map[string]interface{} `json:"tlsSettings,omitempty"`
+	WSSettings          map[string]interface{} `json:"wsSettings,omitempty"`
+	TCPSettings         map[string]interface{} `json:"tcpSettings,omitempty"`
+	GRPCSettings        map[string]interface{} `json:"grpcSettings,omitempty"`
+	HTTPUpgradeSettings map[string]interface{} `json:"httpupgradeSettings,omitempty"`
+}
+
+// DNSConfig 内置DNS配置
+type DNSConfig struct {
+	Servers []interface{} `json:"servers"`
+}
+
+// FakeDNSPoolConfig FakeDNS地址池配置
+type FakeDNSPoolConfig struct {
+	IPPool   string `json:"ipPool"`
+	PoolSize int    `json:"poolSize"`
 }
 
 // RoutingConfig 路由配置
 type RoutingConfig struct {
-	DomainStrategy string      `json:"domainStrategy"`
+	DomainStrategy string       `json:"domainStrategy"`
 	Rules          []RuleConfig `json:"rules"`
 }
 
 // RuleConfig 路由规则配置
 type RuleConfig struct {
 	Type        string   `json:"type"`
+	RuleTag     string   `json:"ruleTag,omitempty"`
 	OutboundTag string   `json:"outboundTag"`
+	InboundTag  []string `json:"inboundTag,omitempty"`
 	Domain      []string `json:"domain,omitempty"`
 	IP          []string `json:"ip,omitempty"`
-}
\ No newline at end of file
+	Port        string   `json:"port,omitempty"`
+	Network     string   `json:"network,omitempty"`
+	Process     []string `json:"process,omitempty"`
+}
@@ -0,0 +1,71 @@
+package proxy
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// xrayVersionPattern 匹配 `xray version` 输出首行，如 "Xray 25.8.3 (Xray, Penetrates Everything.)"
+var xrayVersionPattern = regexp.MustCompile(`Xray\s+v?(\d+\.\d+\.\d+)`)
+
+// singBoxVersionPattern 匹配 `sing-box version` 输出首行，如 "sing-box version 1.11.4"
+var singBoxVersionPattern = regexp.MustCompile(`sing-box version v?(\d+\.\d+\.\d+)`)
+
+// coreVersion 获取Xray内核版本，结果会被缓存
+func (m *XrayProxyManager) coreVersion() (string, error) {
+	m.versionMu.Lock()
+	defer m.versionMu.Unlock()
+
+	if m.version != "" {
+		return m.version, nil
+	}
+
+	version, err := detectVersion(m.xrayPath, xrayVersionPattern)
+	if err != nil {
+		return "", err
+	}
+	m.version = version
+	return version, nil
+}
+
+// detectVersion 运行 `<binary> version` 并按pattern解析版本号
+func detectVersion(binaryPath string, pattern *regexp.Regexp) (string, error) {
+	cmd := exec.Command(binaryPath, "version")
+	hideWindow(cmd)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to run %s version: %w", filepath.Base(binaryPath), err)
+	}
+
+	match := pattern.FindSubmatch(output)
+	if match == nil {
+		return "", fmt.Errorf("unrecognized version output: %s", strings.TrimSpace(string(output)))
+	}
+	return string(match[1]), nil
+}
+
+// compareVersions 比较点分版本号，a<b返回-1，相等返回0，a>b返回1
+func compareVersions(a, b string) int {
+	partsA := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	partsB := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		var numA, numB int
+		if i < len(partsA) {
+			numA, _ = strconv.Atoi(partsA[i])
+		}
+		if i < len(partsB) {
+			numB, _ = strconv.Atoi(partsB[i])
+		}
+		if numA != numB {
+			if numA < numB {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
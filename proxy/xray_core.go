@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"Gox/config"
+	"Gox/server"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// xrayCore Xray内核，直接使用XrayConfig作为配置文件
+type xrayCore struct {
+	manager *XrayProxyManager
+}
+
+// Name 内核名称
+func (c *xrayCore) Name() string {
+	return config.CoreXray
+}
+
+// Binary 返回当前使用的Xray二进制，设置中的二进制路径变化后重新查找
+func (c *xrayCore) Binary() (*CoreInfo, error) {
+	return c.manager.currentXray()
+}
+
+// Capabilities Xray内核能力，新版本Xray已移除h2传输，h2服务器交给sing-box
+func (c *xrayCore) Capabilities() CoreCapabilities {
+	caps := CoreCapabilities{
+		Core:       config.CoreXray,
+		Available:  true,
+		Protocols:  []string{"vmess", "vless", "trojan", "shadowsocks", server.ProtocolCustom},
+		Transports: []string{"tcp", "ws", "grpc", "httpupgrade"},
+		Features:   []string{FeatureFakeDNS, FeatureHotSwitch, FeatureAccessLog, FeatureProcessRule, FeatureStats},
+	}
+	info, err := c.manager.currentXray()
+	if err != nil {
+		caps.Available = false
+		caps.Error = err.Error()
+		return caps
+	}
+	caps.Version = info.Version
+	return caps
+}
+
+// GenerateConfig 序列化Xray配置，合并过自定义片段时输出合并结果
+func (c *xrayCore) GenerateConfig(model *XrayConfig, srv *server.ServerConfig) ([]byte, error) {
+	if model.raw != nil {
+		var out bytes.Buffer
+		if err := json.Indent(&out, model.raw, "", "  "); err != nil {
+			return nil, fmt.Errorf("failed to format xray config: %w", err)
+		}
+		return out.Bytes(), nil
+	}
+
+	data, err := json.MarshalIndent(model, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal xray config: %w", err)
+	}
+	return data, nil
+}
+
+// Command 生成Xray启动命令
+func (c *xrayCore) Command(ctx context.Context, binary, configPath string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, binary, "-config", configPath)
+	hideWindow(cmd)
+	return cmd
+}
+
+// TestConfig 使用Xray的-test参数检查配置文件是否有效
+func (c *xrayCore) TestConfig(binary, configPath string) error {
+	return runConfigTest(config.CoreXray, binary, "-test", "-config", configPath)
+}
+
+// IsReadyLine Xray在所有入站监听成功后输出 "Xray x.y.z started"
+func (c *xrayCore) IsReadyLine(line string) bool {
+	return strings.Contains(line, "Xray") && strings.HasSuffix(strings.TrimSpace(line), "started")
+}
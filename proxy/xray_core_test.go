@@ -0,0 +1,30 @@
+package proxy
+
+import (
+	"Gox/server"
+	"reflect"
+	"testing"
+)
+
+func TestXrayTransportSettings(t *testing.T) {
+	m := &XrayProxyManager{}
+	srv := &server.ServerConfig{Protocol: "vless", Address: "example.com", Port: 443, UUID: "id", Path: "tunnel", Host: "cdn.example.com"}
+
+	srv.Network = "grpc"
+	stream := m.generateOutboundConfig(srv).StreamSettings
+	if want := map[string]interface{}{"serviceName": "tunnel"}; stream == nil || !reflect.DeepEqual(stream.GRPCSettings, want) {
+		t.Errorf("grpc stream settings = %+v", stream)
+	}
+
+	srv.Network = "httpupgrade"
+	stream = m.generateOutboundConfig(srv).StreamSettings
+	if want := map[string]interface{}{"path": "tunnel", "host": "cdn.example.com"}; stream == nil || !reflect.DeepEqual(stream.HTTPUpgradeSettings, want) {
+		t.Errorf("httpupgrade stream settings = %+v", stream)
+	}
+
+	// h2不在Xray支持的传输中，自动选择内核时改用sing-box
+	caps := (&xrayCore{manager: m}).Capabilities()
+	if caps.supports("vless", "h2") || !caps.supports("vless", "grpc") {
+		t.Errorf("unexpected xray transports %v", caps.Transports)
+	}
+}
@@ -2,23 +2,46 @@ package server
 
 import "time"
 
+// ProtocolCustom 使用用户提供的完整内核配置的服务器
+const ProtocolCustom = "custom"
+
 // ServerConfig 服务器配置结构体
 type ServerConfig struct {
-	ID       string    `json:"id"`       // 服务器唯一标识
-	Name     string    `json:"name"`     // 服务器名称
-	Protocol string    `json:"protocol"` // 协议类型 (vmess, vless, trojan, shadowsocks)
-	Address  string    `json:"address"`  // 服务器地址
-	Port     int       `json:"port"`     // 服务器端口
-	UUID     string    `json:"uuid"`     // UUID (vmess/vless)
-	Password string    `json:"password"` // 密码 (trojan/shadowsocks)
-	Method   string    `json:"method"`   // 加密方法 (shadowsocks)
-	Network  string    `json:"network"`  // 传输协议 (tcp, ws, grpc)
-	Path     string    `json:"path"`     // 路径 (websocket)
-	Host     string    `json:"host"`     // 主机名 (websocket)
-	TLS      bool      `json:"tls"`      // 是否启用TLS
-	SNI      string    `json:"sni"`      // SNI
-	Created  time.Time `json:"created"`  // 创建时间
-	Updated  time.Time `json:"updated"`  // 更新时间
+	ID       string   `json:"id"`       // 服务器唯一标识
+	Name     string   `json:"name"`     // 服务器名称
+	Protocol string   `json:"protocol"` // 协议类型 (vmess, vless, trojan, shadowsocks, hysteria2, tuic, custom)
+	Address  string   `json:"address"`  // 服务器地址
+	Port     int      `json:"port"`     // 服务器端口
+	UUID     string   `json:"uuid"`     // UUID (vmess/vless/tuic)
+	Password string   `json:"password"` // 密码 (trojan/shadowsocks/hysteria2/tuic)
+	Method   string   `json:"method"`   // 加密方法 (shadowsocks)
+	Network  string   `json:"network"`  // 传输协议 (tcp, ws, grpc)
+	Path     string   `json:"path"`     // 路径 (websocket)
+	Host     string   `json:"host"`     // 主机名 (websocket)
+	TLS      bool     `json:"tls"`      // 是否启用TLS
+	SNI      string   `json:"sni"`      // SNI
+	ALPN     []string `json:"alpn"`     // TLS ALPN
+	Insecure bool     `json:"insecure"` // 跳过证书校验
+	Core     string   `json:"core"`     // 使用的内核 (xray, sing-box)，为空时使用全局设置
+
+	MonthlyQuotaMB int    `json:"monthlyQuotaMB"` // 每月流量配额(MB)，超过时提醒，0表示不限制
+	SubscriptionID string `json:"subscriptionId"` // 所属订阅ID，流量同时计入该订阅，为空表示不属于任何订阅
+
+	// Hysteria2
+	Obfs         string `json:"obfs"`         // 混淆类型 (salamander)
+	ObfsPassword string `json:"obfsPassword"` // 混淆密码
+	UpMbps       int    `json:"upMbps"`       // 上行带宽(Mbps)，0表示由服务端决定
+	DownMbps     int    `json:"downMbps"`     // 下行带宽(Mbps)，0表示由服务端决定
+
+	// TUIC
+	CongestionControl string `json:"congestionControl"` // 拥塞控制 (cubic, new_reno, bbr)
+	UDPRelayMode      string `json:"udpRelayMode"`      // UDP转发模式 (native, quic)
+
+	// 完整配置
+	Config string `json:"config"` // 完整的内核配置JSON，仅custom协议使用，原样交给内核运行
+
+	Created time.Time `json:"created"` // 创建时间
+	Updated time.Time `json:"updated"` // 更新时间
 }
 
 // ServerManager 服务器管理器接口
@@ -39,4 +62,4 @@ type ServerManager interface {
 	ListServers() ([]*ServerConfig, error)
 	// ValidateServerName 验证服务器名称是否重复
 	ValidateServerName(name string, excludeID string) error
-}
\ No newline at end of file
+}
@@ -0,0 +1,298 @@
+package subscription
+
+import (
+	"Gox/config"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// fetchTimeout 下载订阅的超时时间
+	fetchTimeout = 30 * time.Second
+	// retryInterval 更新失败后重试的间隔
+	retryInterval = time.Hour
+	// userAgent 下载订阅时使用的User-Agent
+	userAgent = "Gox"
+)
+
+// Manager 订阅管理器，订阅列表保存在单个JSON文件中
+type Manager struct {
+	mu            sync.Mutex
+	path          string
+	client        *http.Client
+	subscriptions []*Subscription
+}
+
+// NewManager 创建订阅管理器，client为空时使用默认的HTTP客户端
+func NewManager(path string, client *http.Client) (*Manager, error) {
+	if client == nil {
+		client = &http.Client{Timeout: fetchTimeout}
+	}
+	manager := &Manager{path: path, client: client}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return manager, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read subscriptions: %w", err)
+	}
+	if err := json.Unmarshal(data, &manager.subscriptions); err != nil {
+		return nil, fmt.Errorf("failed to decode subscriptions: %w", err)
+	}
+	return manager, nil
+}
+
+// settings 获取订阅配置，配置未加载时使用默认值
+func settings() config.SubscriptionConfig {
+	if cfg := config.GetConfig(); cfg != nil {
+		return cfg.Subscription
+	}
+	return config.GetDefaultConfig().Subscription
+}
+
+// List 列出所有订阅及其剩余流量和到期状态
+func (m *Manager) List() []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	warningDays := settings().ExpiryWarningDays
+	statuses := make([]Status, 0, len(m.subscriptions))
+	for _, sub := range m.subscriptions {
+		statuses = append(statuses, sub.status(now, warningDays))
+	}
+	return statuses
+}
+
+// Get 按ID获取订阅
+func (m *Manager) Get(id string) (*Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sub := m.findLocked(id)
+	if sub == nil {
+		return nil, fmt.Errorf("订阅不存在: %s", id)
+	}
+	copied := *sub
+	return &copied, nil
+}
+
+// Add 添加订阅，添加后需要调用Refresh获取流量和到期信息
+func (m *Manager) Add(name, rawURL string) (*Subscription, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("订阅地址必须是http或https链接")
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = u.Host
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, sub := range m.subscriptions {
+		if sub.URL == rawURL {
+			return nil, fmt.Errorf("订阅 '%s' 已存在", sub.Name)
+		}
+	}
+	sub := &Subscription{
+		ID:      uuid.New().String(),
+		Name:    name,
+		URL:     rawURL,
+		Created: time.Now(),
+	}
+	m.subscriptions = append(m.subscriptions, sub)
+	if err := m.saveLocked(); err != nil {
+		m.subscriptions = m.subscriptions[:len(m.subscriptions)-1]
+		return nil, err
+	}
+	copied := *sub
+	return &copied, nil
+}
+
+// Remove 删除订阅
+func (m *Manager) Remove(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, sub := range m.subscriptions {
+		if sub.ID == id {
+			m.subscriptions = append(m.subscriptions[:i], m.subscriptions[i+1:]...)
+			return m.saveLocked()
+		}
+	}
+	return fmt.Errorf("订阅不存在: %s", id)
+}
+
+// Refresh 下载订阅并更新流量、到期信息和下次更新时间
+// 下次更新时间优先使用服务商通过 profile-update-interval 要求的间隔
+func (m *Manager) Refresh(id string) (*Status, error) {
+	m.mu.Lock()
+	sub := m.findLocked(id)
+	if sub == nil {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("订阅不存在: %s", id)
+	}
+	subURL := sub.URL
+	m.mu.Unlock()
+
+	info, interval, fetchErr := m.fetch(subURL)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// 下载期间订阅可能已被删除
+	if sub = m.findLocked(id); sub == nil {
+		return nil, fmt.Errorf("订阅不存在: %s", id)
+	}
+	now := time.Now()
+	cfg := settings()
+	if fetchErr != nil {
+		sub.LastError = fetchErr.Error()
+		sub.NextUpdate = now.Add(min(retryInterval, sub.interval(cfg)))
+	} else {
+		sub.UserInfo = info
+		sub.UpdateInterval = interval
+		sub.UpdatedAt = now
+		sub.NextUpdate = now.Add(sub.interval(cfg))
+		sub.LastError = ""
+	}
+	if err := m.saveLocked(); err != nil {
+		return nil, err
+	}
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+	status := sub.status(now, cfg.ExpiryWarningDays)
+	return &status, nil
+}
+
+// RefreshDue 更新所有已到更新时间的订阅，返回更新失败的错误
+func (m *Manager) RefreshDue() []error {
+	m.mu.Lock()
+	now := time.Now()
+	var due []string
+	for _, sub := range m.subscriptions {
+		if !sub.NextUpdate.After(now) {
+			due = append(due, sub.ID)
+		}
+	}
+	m.mu.Unlock()
+
+	var errs []error
+	for _, id := range due {
+		if _, err := m.Refresh(id); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// ExpiryWarnings 返回即将到期或已到期且尚未提醒过的订阅，并记录为已提醒
+func (m *Manager) ExpiryWarnings() ([]Status, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	warningDays := settings().ExpiryWarningDays
+	if warningDays <= 0 {
+		return nil, nil
+	}
+
+	var warnings []Status
+	for _, sub := range m.subscriptions {
+		status := sub.status(now, warningDays)
+		if !status.ExpiringSoon && !status.Expired {
+			continue
+		}
+		if sub.ExpiryWarned.Equal(sub.UserInfo.Expire) {
+			continue
+		}
+		sub.ExpiryWarned = sub.UserInfo.Expire
+		status.ExpiryWarned = sub.ExpiryWarned
+		warnings = append(warnings, status)
+	}
+	if len(warnings) == 0 {
+		return nil, nil
+	}
+	return warnings, m.saveLocked()
+}
+
+// interval 订阅的更新间隔，服务商未指定时使用配置中的默认间隔
+func (s *Subscription) interval(cfg config.SubscriptionConfig) time.Duration {
+	hours := s.UpdateInterval
+	if hours <= 0 {
+		hours = cfg.UpdateInterval
+	}
+	if hours <= 0 {
+		hours = config.DefaultSubscriptionUpdateInterval
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// fetch 下载订阅并解析响应头中的流量、到期信息和更新间隔
+func (m *Manager) fetch(subURL string) (*UserInfo, int, error) {
+	req, err := http.NewRequest(http.MethodGet, subURL, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create subscription request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to download subscription: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("failed to download subscription: unexpected status %s", resp.Status)
+	}
+
+	var info *UserInfo
+	if header := resp.Header.Get("Subscription-Userinfo"); header != "" {
+		if info, err = ParseUserInfo(header); err != nil {
+			return nil, 0, err
+		}
+	}
+	return info, ParseUpdateInterval(resp.Header.Get("Profile-Update-Interval")), nil
+}
+
+// findLocked 按ID查找订阅（调用方需持有锁）
+func (m *Manager) findLocked(id string) *Subscription {
+	for _, sub := range m.subscriptions {
+		if sub.ID == id {
+			return sub
+		}
+	}
+	return nil
+}
+
+// saveLocked 保存订阅列表（调用方需持有锁）
+func (m *Manager) saveLocked() error {
+	data, err := json.MarshalIndent(m.subscriptions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal subscriptions: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return fmt.Errorf("failed to create subscription directory: %w", err)
+	}
+	if err := os.WriteFile(m.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write subscriptions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package subscription
+
+import "time"
+
+// Subscription 订阅
+type Subscription struct {
+	ID   string `json:"id"`   // 订阅唯一标识
+	Name string `json:"name"` // 订阅名称
+	URL  string `json:"url"`  // 订阅地址
+
+	UserInfo       *UserInfo `json:"userInfo,omitempty"` // 服务商返回的流量和到期信息，未返回时为空
+	UpdateInterval int       `json:"updateInterval"`     // 服务商要求的更新间隔(小时)，0表示使用默认间隔
+	UpdatedAt      time.Time `json:"updatedAt"`          // 上次成功更新的时间
+	NextUpdate     time.Time `json:"nextUpdate"`         // 下次更新时间
+	LastError      string    `json:"lastError"`          // 上次更新失败的原因
+	ExpiryWarned   time.Time `json:"expiryWarned"`       // 已提醒过的到期时间，到期时间变化后重新提醒
+
+	Created time.Time `json:"created"` // 创建时间
+}
+
+// UserInfo subscription-userinfo 响应头中的流量和到期信息
+type UserInfo struct {
+	Upload   int64     `json:"upload"`   // 已用上传字节数
+	Download int64     `json:"download"` // 已用下载字节数
+	Total    int64     `json:"total"`    // 总流量字节数，0表示不限流量
+	Expire   time.Time `json:"expire"`   // 到期时间，零值表示不过期
+}
+
+// Status 订阅及其剩余流量和到期状态
+type Status struct {
+	Subscription
+	Used         int64 `json:"used"`         // 已用流量字节数
+	Remaining    int64 `json:"remaining"`    // 剩余流量字节数，-1表示不限流量或未知
+	DaysLeft     int   `json:"daysLeft"`     // 距到期的天数，-1表示不过期或未知
+	ExpiringSoon bool  `json:"expiringSoon"` // 是否即将到期
+	Expired      bool  `json:"expired"`      // 是否已到期
+}
+
+// status 计算订阅在指定时间的状态，warningDays为到期前开始提醒的天数
+func (s *Subscription) status(now time.Time, warningDays int) Status {
+	status := Status{Subscription: *s, Remaining: -1, DaysLeft: -1}
+	info := s.UserInfo
+	if info == nil {
+		return status
+	}
+
+	status.Used = info.Upload + info.Download
+	if info.Total > 0 {
+		status.Remaining = max(info.Total-status.Used, 0)
+	}
+	if !info.Expire.IsZero() {
+		left := info.Expire.Sub(now)
+		status.Expired = left <= 0
+		// 不足一天按一天计算
+		status.DaysLeft = max(int((left+24*time.Hour-1)/(24*time.Hour)), 0)
+		status.ExpiringSoon = !status.Expired && warningDays > 0 && status.DaysLeft <= warningDays
+	}
+	return status
+}
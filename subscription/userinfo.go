@@ -0,0 +1,73 @@
+package subscription
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseUserInfo 解析 subscription-userinfo 响应头，如 upload=455727941; download=6174315083; total=1073741824000; expire=1671815872
+// 未知字段会被忽略，expire为0或缺失表示不过期
+func ParseUserInfo(header string) (*UserInfo, error) {
+	info := &UserInfo{}
+	found := false
+	for _, field := range strings.Split(header, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key != "upload" && key != "download" && key != "total" && key != "expire" {
+			continue
+		}
+
+		number, err := parseNumber(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subscription-userinfo field %s: %w", key, err)
+		}
+		found = true
+		switch key {
+		case "upload":
+			info.Upload = number
+		case "download":
+			info.Download = number
+		case "total":
+			info.Total = number
+		case "expire":
+			if number > 0 {
+				info.Expire = time.Unix(number, 0)
+			}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("invalid subscription-userinfo %q", header)
+	}
+	return info, nil
+}
+
+// ParseUpdateInterval 解析 profile-update-interval 响应头(小时)，无效时返回0
+func ParseUpdateInterval(header string) int {
+	hours, err := strconv.Atoi(strings.TrimSpace(header))
+	if err != nil || hours <= 0 {
+		return 0
+	}
+	return hours
+}
+
+// parseNumber 解析非负整数，部分服务商会返回浮点数或科学计数法
+func parseNumber(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	if number, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return max(number, 0), nil
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0, fmt.Errorf("%q is not a number", value)
+	}
+	return max(int64(number), 0), nil
+}
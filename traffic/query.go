@@ -0,0 +1,165 @@
+package traffic
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// 流量查询的统计对象
+const (
+	GroupByServer       = "server"       // 按服务器
+	GroupBySubscription = "subscription" // 按订阅
+)
+
+// UsageQuery 流量查询条件
+type UsageQuery struct {
+	GroupBy        string    `json:"groupBy"`        // server, subscription，默认server
+	ServerID       string    `json:"serverId"`       // 服务器ID，为空时查询所有服务器
+	SubscriptionID string    `json:"subscriptionId"` // 订阅ID，按订阅统计时有效，为空时查询所有订阅
+	Granularity    string    `json:"granularity"`    // hour, day, month，默认day
+	From           time.Time `json:"from"`           // 起始时间（包含），零值表示不限制
+	To             time.Time `json:"to"`             // 结束时间（不包含），零值表示不限制
+}
+
+// UsagePoint 一个时间桶内单个服务器或订阅的流量
+type UsagePoint struct {
+	ServerID         string    `json:"serverId"`         // 服务器ID，按订阅统计时为空
+	ServerName       string    `json:"serverName"`       // 服务器名称
+	SubscriptionID   string    `json:"subscriptionId"`   // 订阅ID，按服务器统计时为空
+	SubscriptionName string    `json:"subscriptionName"` // 订阅名称
+	Period           string    `json:"period"`           // 时间桶，如 2024-05-01T08、2024-05-01、2024-05
+	Start            time.Time `json:"start"`            // 时间桶的开始时间
+	Upload           int64     `json:"upload"`           // 上传字节数
+	Download         int64     `json:"download"`         // 下载字节数
+	Total            int64     `json:"total"`            // 总字节数
+}
+
+// Query 按条件查询流量，结果按时间和服务器或订阅名称排序
+func (s *Store) Query(query UsageQuery) ([]UsagePoint, error) {
+	layout, err := granularityLayout(query.Granularity)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, filter := s.servers, query.ServerID
+	switch query.GroupBy {
+	case GroupByServer, "":
+	case GroupBySubscription:
+		records, filter = s.subscriptions, query.SubscriptionID
+	default:
+		return nil, fmt.Errorf("unknown traffic group %s", query.GroupBy)
+	}
+
+	points := []UsagePoint{}
+	for id, usage := range records {
+		if filter != "" && id != filter {
+			continue
+		}
+		counters := usage.Daily
+		switch layout {
+		case hourLayout:
+			counters = usage.Hourly
+		case monthLayout:
+			counters = usage.Monthly
+		}
+
+		for _, key := range sortedKeys(counters) {
+			start, err := time.ParseInLocation(layout, key, time.Local)
+			if err != nil {
+				continue
+			}
+			// 时间桶与查询范围有重叠即返回
+			if !query.To.IsZero() && !start.Before(query.To) {
+				continue
+			}
+			if !query.From.IsZero() && !bucketEnd(start, layout).After(query.From) {
+				continue
+			}
+			counter := counters[key]
+			point := UsagePoint{
+				Period:   key,
+				Start:    start,
+				Upload:   counter.Upload,
+				Download: counter.Download,
+				Total:    counter.Total(),
+			}
+			if query.GroupBy == GroupBySubscription {
+				point.SubscriptionID, point.SubscriptionName = id, usage.Name
+			} else {
+				point.ServerID, point.ServerName = id, usage.Name
+			}
+			points = append(points, point)
+		}
+	}
+
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].Period != points[j].Period {
+			return points[i].Period < points[j].Period
+		}
+		return points[i].ServerName+points[i].SubscriptionName < points[j].ServerName+points[j].SubscriptionName
+	})
+	return points, nil
+}
+
+// ExportCSV 将查询结果导出为CSV
+func (s *Store) ExportCSV(w io.Writer, query UsageQuery) error {
+	points, err := s.Query(query)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"period", "server_id", "server_name", "subscription_id", "subscription_name", "upload_bytes", "download_bytes", "total_bytes"}); err != nil {
+		return fmt.Errorf("failed to write csv header: %w", err)
+	}
+	for _, point := range points {
+		record := []string{
+			point.Period,
+			point.ServerID,
+			point.ServerName,
+			point.SubscriptionID,
+			point.SubscriptionName,
+			strconv.FormatInt(point.Upload, 10),
+			strconv.FormatInt(point.Download, 10),
+			strconv.FormatInt(point.Total, 10),
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write csv record: %w", err)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// granularityLayout 统计粒度对应的桶键格式
+func granularityLayout(granularity string) (string, error) {
+	switch granularity {
+	case GranularityHour:
+		return hourLayout, nil
+	case GranularityDay, "":
+		return dayLayout, nil
+	case GranularityMonth:
+		return monthLayout, nil
+	default:
+		return "", fmt.Errorf("unknown traffic granularity %s", granularity)
+	}
+}
+
+// bucketEnd 时间桶的结束时间
+func bucketEnd(start time.Time, layout string) time.Time {
+	switch layout {
+	case hourLayout:
+		return start.Add(time.Hour)
+	case monthLayout:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
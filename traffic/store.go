@@ -0,0 +1,322 @@
+package traffic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 统计粒度
+const (
+	GranularityHour  = "hour"  // 按小时
+	GranularityDay   = "day"   // 按天
+	GranularityMonth = "month" // 按月
+)
+
+// 各粒度桶的键格式，使用本地时间，字符串顺序即时间顺序
+const (
+	hourLayout  = "2006-01-02T15"
+	dayLayout   = "2006-01-02"
+	monthLayout = "2006-01"
+)
+
+const (
+	// hourlyRetention 小时桶保留时间
+	hourlyRetention = 7 * 24 * time.Hour
+	// dailyRetention 日桶保留时间
+	dailyRetention = 400 * 24 * time.Hour
+	// saveInterval 记录流量后写入文件的最小间隔
+	saveInterval = time.Minute
+)
+
+// Counter 流量计数
+type Counter struct {
+	Upload   int64 `json:"upload"`   // 上传字节数
+	Download int64 `json:"download"` // 下载字节数
+}
+
+// Total 上传和下载的总字节数
+func (c Counter) Total() int64 {
+	return c.Upload + c.Download
+}
+
+// add 累加流量
+func (c *Counter) add(upload, download int64) {
+	c.Upload += upload
+	c.Download += download
+}
+
+// usageRecord 单个服务器或订阅的流量记录
+type usageRecord struct {
+	Name         string              `json:"name"`         // 最近一次记录时的服务器或订阅名称
+	Hourly       map[string]*Counter `json:"hourly"`       // 小时桶
+	Daily        map[string]*Counter `json:"daily"`        // 日桶
+	Monthly      map[string]*Counter `json:"monthly"`      // 月桶
+	AlertedMonth string              `json:"alertedMonth"` // 已发出配额提醒的月份，仅服务器使用
+}
+
+// storeFile 流量统计文件的内容
+type storeFile struct {
+	Servers       map[string]*usageRecord `json:"servers"`       // 按服务器ID
+	Subscriptions map[string]*usageRecord `json:"subscriptions"` // 按订阅ID，汇总订阅下所有服务器的流量
+}
+
+// Sample 一次流量采样
+type Sample struct {
+	ServerID   string // 服务器ID
+	ServerName string // 服务器名称
+	// 服务器所属的订阅，为空时只计入服务器
+	SubscriptionID   string
+	SubscriptionName string
+	Upload           int64     // 新增上传字节数
+	Download         int64     // 新增下载字节数
+	Quota            int64     // 服务器每月流量配额（字节），0表示不限制
+	Time             time.Time // 采样时间
+}
+
+// QuotaAlert 服务器本月流量超过配额
+type QuotaAlert struct {
+	ServerID   string `json:"serverId"`   // 服务器ID
+	ServerName string `json:"serverName"` // 服务器名称
+	Month      string `json:"month"`      // 月份，如 2024-05
+	Used       int64  `json:"used"`       // 本月已用字节数
+	Quota      int64  `json:"quota"`      // 配额字节数
+}
+
+// Store 按服务器和订阅统计流量，每次记录同时累加到小时、日、月三级桶
+// 过期的小时桶和日桶直接删除，其流量已汇总在上一级桶中
+type Store struct {
+	mu            sync.Mutex
+	path          string
+	servers       map[string]*usageRecord
+	subscriptions map[string]*usageRecord
+	dirty         bool
+	lastSave      time.Time
+}
+
+// NewStore 创建流量统计存储，数据保存在指定文件中
+func NewStore(path string) (*Store, error) {
+	store := &Store{
+		path:          path,
+		servers:       make(map[string]*usageRecord),
+		subscriptions: make(map[string]*usageRecord),
+		lastSave:      time.Now(),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read traffic store: %w", err)
+	}
+	var file storeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode traffic store: %w", err)
+	}
+	for id, usage := range file.Servers {
+		usage.ensureBuckets()
+		store.servers[id] = usage
+	}
+	for id, usage := range file.Subscriptions {
+		usage.ensureBuckets()
+		store.subscriptions[id] = usage
+	}
+	return store, nil
+}
+
+// ensureBuckets 补全文件中缺失的桶
+func (u *usageRecord) ensureBuckets() {
+	if u.Hourly == nil {
+		u.Hourly = make(map[string]*Counter)
+	}
+	if u.Daily == nil {
+		u.Daily = make(map[string]*Counter)
+	}
+	if u.Monthly == nil {
+		u.Monthly = make(map[string]*Counter)
+	}
+}
+
+// Record 记录一次流量采样，同时计入所属订阅，服务器本月流量首次超过配额时返回提醒
+func (s *Store) Record(sample Sample) (*QuotaAlert, error) {
+	if sample.ServerID == "" || (sample.Upload <= 0 && sample.Download <= 0) {
+		return nil, nil
+	}
+	if sample.Time.IsZero() {
+		sample.Time = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	local := sample.Time.Local()
+	month := local.Format(monthLayout)
+	usage := recordFor(s.servers, sample.ServerID, sample.ServerName)
+	usage.add(local, sample.Upload, sample.Download)
+	if sample.SubscriptionID != "" {
+		recordFor(s.subscriptions, sample.SubscriptionID, sample.SubscriptionName).add(local, sample.Upload, sample.Download)
+	}
+	s.dirty = true
+
+	var alert *QuotaAlert
+	if used := usage.Monthly[month].Total(); sample.Quota > 0 && used >= sample.Quota && usage.AlertedMonth != month {
+		usage.AlertedMonth = month
+		alert = &QuotaAlert{
+			ServerID:   sample.ServerID,
+			ServerName: usage.Name,
+			Month:      month,
+			Used:       used,
+			Quota:      sample.Quota,
+		}
+	}
+
+	// 配额提醒需要立即保存，避免重启后重复提醒
+	if alert != nil || time.Since(s.lastSave) >= saveInterval {
+		return alert, s.saveLocked()
+	}
+	return alert, nil
+}
+
+// recordFor 获取流量记录，不存在时创建，名称非空时更新为最新名称
+func recordFor(records map[string]*usageRecord, id, name string) *usageRecord {
+	usage := records[id]
+	if usage == nil {
+		usage = &usageRecord{}
+		usage.ensureBuckets()
+		records[id] = usage
+	}
+	if name != "" {
+		usage.Name = name
+	}
+	return usage
+}
+
+// add 将流量累加到本地时间所在的小时、日、月桶
+func (u *usageRecord) add(local time.Time, upload, download int64) {
+	for _, bucket := range []struct {
+		counters map[string]*Counter
+		key      string
+	}{
+		{u.Hourly, local.Format(hourLayout)},
+		{u.Daily, local.Format(dayLayout)},
+		{u.Monthly, local.Format(monthLayout)},
+	} {
+		counter := bucket.counters[bucket.key]
+		if counter == nil {
+			counter = &Counter{}
+			bucket.counters[bucket.key] = counter
+		}
+		counter.add(upload, download)
+	}
+}
+
+// MonthlyUsage 获取服务器指定月份的流量
+func (s *Store) MonthlyUsage(serverID string, month time.Time) Counter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return monthlyUsage(s.servers[serverID], month)
+}
+
+// SubscriptionMonthlyUsage 获取订阅下所有服务器指定月份的流量
+func (s *Store) SubscriptionMonthlyUsage(subscriptionID string, month time.Time) Counter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return monthlyUsage(s.subscriptions[subscriptionID], month)
+}
+
+// monthlyUsage 获取记录中指定月份的流量
+func monthlyUsage(usage *usageRecord, month time.Time) Counter {
+	if usage == nil {
+		return Counter{}
+	}
+	if counter := usage.Monthly[month.Local().Format(monthLayout)]; counter != nil {
+		return *counter
+	}
+	return Counter{}
+}
+
+// Remove 删除服务器的流量记录
+func (s *Store) Remove(serverID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.servers[serverID]; !ok {
+		return nil
+	}
+	delete(s.servers, serverID)
+	s.dirty = true
+	return s.saveLocked()
+}
+
+// Flush 将未保存的记录写入文件
+func (s *Store) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.dirty {
+		return nil
+	}
+	return s.saveLocked()
+}
+
+// saveLocked 删除过期的桶后写入文件（调用方需持有锁）
+func (s *Store) saveLocked() error {
+	s.pruneLocked(time.Now())
+
+	data, err := json.MarshalIndent(storeFile{Servers: s.servers, Subscriptions: s.subscriptions}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal traffic store: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create traffic directory: %w", err)
+	}
+
+	// 先写临时文件再替换，避免中断时损坏已有记录
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write traffic store: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace traffic store: %w", err)
+	}
+	s.dirty = false
+	s.lastSave = time.Now()
+	return nil
+}
+
+// pruneLocked 删除超过保留时间的小时桶和日桶（调用方需持有锁）
+func (s *Store) pruneLocked(now time.Time) {
+	hourCutoff := now.Add(-hourlyRetention).Local().Format(hourLayout)
+	dayCutoff := now.Add(-dailyRetention).Local().Format(dayLayout)
+	for _, records := range []map[string]*usageRecord{s.servers, s.subscriptions} {
+		for _, usage := range records {
+			pruneBuckets(usage.Hourly, hourCutoff)
+			pruneBuckets(usage.Daily, dayCutoff)
+		}
+	}
+}
+
+// pruneBuckets 删除键早于cutoff的桶
+func pruneBuckets(counters map[string]*Counter, cutoff string) {
+	for key := range counters {
+		if key < cutoff {
+			delete(counters, key)
+		}
+	}
+}
+
+// sortedKeys 按时间顺序排列的桶键
+func sortedKeys(counters map[string]*Counter) []string {
+	keys := make([]string, 0, len(counters))
+	for key := range counters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,85 @@
+package traffic
+
+import (
+	"bytes"
+	"encoding/csv"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSubscriptionTotals(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traffic.json")
+	store, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+
+	now := time.Date(2024, 5, 10, 8, 30, 0, 0, time.Local)
+	samples := []Sample{
+		{ServerID: "a", ServerName: "A", SubscriptionID: "sub", SubscriptionName: "Provider", Upload: 100, Download: 1000, Time: now},
+		{ServerID: "b", ServerName: "B", SubscriptionID: "sub", SubscriptionName: "Provider", Upload: 200, Download: 2000, Time: now.Add(time.Hour)},
+		{ServerID: "c", ServerName: "C", Upload: 300, Download: 3000, Time: now},
+		// 上个月的流量不计入本月
+		{ServerID: "a", ServerName: "A", SubscriptionID: "sub", SubscriptionName: "Provider", Upload: 5, Download: 5, Time: now.AddDate(0, -1, 0)},
+	}
+	for _, sample := range samples {
+		if _, err := store.Record(sample); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	if got := store.SubscriptionMonthlyUsage("sub", now); got != (Counter{Upload: 300, Download: 3000}) {
+		t.Errorf("subscription usage = %+v, want 300/3000", got)
+	}
+	if got := store.MonthlyUsage("a", now); got != (Counter{Upload: 100, Download: 1000}) {
+		t.Errorf("server usage = %+v, want 100/1000", got)
+	}
+
+	points, err := store.Query(UsageQuery{GroupBy: GroupBySubscription, Granularity: GranularityMonth})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(points) != 2 {
+		t.Fatalf("got %d subscription points, want 2: %+v", len(points), points)
+	}
+	point := points[1]
+	if point.SubscriptionID != "sub" || point.SubscriptionName != "Provider" || point.Period != "2024-05" ||
+		point.Total != 3300 || point.ServerID != "" {
+		t.Errorf("unexpected subscription point %+v", point)
+	}
+
+	points, err = store.Query(UsageQuery{GroupBy: GroupBySubscription, SubscriptionID: "other", Granularity: GranularityMonth})
+	if err != nil || len(points) != 0 {
+		t.Errorf("query of unknown subscription = %+v, %v", points, err)
+	}
+	if _, err := store.Query(UsageQuery{GroupBy: "provider"}); err == nil {
+		t.Error("expected an error for an unknown group")
+	}
+
+	// 订阅的汇总随文件一起保存
+	if err := store.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	reloaded, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if got := reloaded.SubscriptionMonthlyUsage("sub", now); got.Total() != 3300 {
+		t.Errorf("reloaded subscription total = %d, want 3300", got.Total())
+	}
+
+	var buf bytes.Buffer
+	if err := reloaded.ExportCSV(&buf, UsageQuery{GroupBy: GroupBySubscription, Granularity: GranularityMonth, From: now}); err != nil {
+		t.Fatalf("ExportCSV: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("read csv: %v", err)
+	}
+	want := []string{"2024-05", "", "", "sub", "Provider", "300", "3000", "3300"}
+	if len(records) != 2 || !slices.Equal(records[1], want) {
+		t.Errorf("csv = %v, want header and %v", records, want)
+	}
+}